@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+// AnalysisLines 是分析模式下显示的变例数
+const AnalysisLines = 3
+
+// analysisMoves 是每条变例最多显示的着法数
+const analysisMoves = 6
+
+// analyzer 在后台对当前局面做无限分析，局面变化时重新开始
+type analyzer struct {
+	engine  *engine.Engine
+	running *engine.Analysis
+	hash    uint64
+	ply     int
+	turn    bool
+	lines   [AnalysisLines]*engine.Info
+	enabled bool
+}
+
+func newAnalyzer() *analyzer {
+	e := engine.NewEngine()
+	e.SetOption("MultiPV", fmt.Sprint(AnalysisLines))
+	return &analyzer{engine: e}
+}
+
+func (a *analyzer) stop() {
+	if a.running == nil {
+		return
+	}
+	a.engine.Stop()
+	for range a.running.Infos {
+	}
+	<-a.running.Result
+	a.running = nil
+	a.lines = [AnalysisLines]*engine.Info{}
+}
+
+// update 读取已经输出的分析结果，局面变化时重新开始分析
+func (a *analyzer) update(b *chess.Board, over bool) {
+	if !a.enabled || over {
+		a.stop()
+		return
+	}
+	if a.running != nil && (a.hash != b.Hash() || a.ply != b.Ply()) {
+		a.stop()
+	}
+	if a.running == nil {
+		a.hash, a.ply, a.turn = b.Hash(), b.Ply(), b.Turn()
+		a.running = a.engine.Analyze(b, engine.Limits{Infinite: true})
+	}
+	for {
+		select {
+		case info, ok := <-a.running.Infos:
+			if !ok {
+				return
+			}
+			if info.MultiPV >= 1 && info.MultiPV <= AnalysisLines {
+				info := info
+				a.lines[info.MultiPV-1] = &info
+			}
+			continue
+		default:
+		}
+		return
+	}
+}
+
+// Lines 返回显示用的分析结果，分数以红方为视角
+func (a *analyzer) Lines() []string {
+	var lines []string
+	for _, info := range a.lines {
+		if info == nil {
+			continue
+		}
+		score := fmt.Sprintf("%+d", info.Score)
+		if a.turn == chess.Black {
+			score = fmt.Sprintf("%+d", -info.Score)
+		}
+		if mate := info.Mate(); mate != 0 {
+			if (mate > 0) == (a.turn == chess.Red) {
+				score = fmt.Sprintf("红胜 %d", abs(mate))
+			} else {
+				score = fmt.Sprintf("黑胜 %d", abs(mate))
+			}
+		}
+		var moves []string
+		for i, move := range info.PV {
+			if i >= analysisMoves {
+				break
+			}
+			moves = append(moves, move.String())
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s  %s", info.MultiPV, score, strings.Join(moves, " ")))
+	}
+	return lines
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
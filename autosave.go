@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/pgn"
+)
+
+// AutosaveFile 是自动保存的棋谱，位于用户配置目录下的 gochess 目录中
+const AutosaveFile = "autosave.pgn"
+
+func autosavePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gochess", AutosaveFile), nil
+}
+
+// saveGame 把棋谱写到 path，先写临时文件再改名，避免中途退出时留下不完整的文件
+func saveGame(path string, record *game.Game) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := pgn.Write(f, record); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadGame(path string) (*game.Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return pgn.NewReader(f).Read()
+}
+
+// autosave 在每一步之后保存当前的对局
+func (g *Game) autosave() {
+	path, err := autosavePath()
+	if err == nil {
+		err = saveGame(path, g.record.Game())
+	}
+	if err != nil {
+		log.Printf("autosave: %v", err)
+	}
+}
+
+// loadAutosave 读取上次没有下完的对局，没有时返回 nil
+func loadAutosave() *game.Game {
+	path, err := autosavePath()
+	if err != nil {
+		return nil
+	}
+	record, err := loadGame(path)
+	if err != nil || record.Result != chess.NoResult || len(record.MainLine()) == 0 {
+		return nil
+	}
+	return record
+}
+
+// Resume 继续上次没有下完的对局
+func (g *Game) Resume() {
+	if g.saved == nil {
+		return
+	}
+	g.start(g.saved, settingsFromTags(g.saved))
+	g.saved = nil
+}
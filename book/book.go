@@ -0,0 +1,233 @@
+package book
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 开局库文件格式（小端序）：
+//
+//	magic   [4]byte "GCBK"
+//	version uint32
+//	count   uint32
+//	count 条记录，按 key 升序排列，同一 key 按权重降序：
+//	  key    uint64
+//	  from   uint8
+//	  to     uint8
+//	  weight uint32
+//	  wins   uint32
+//	  draws  uint32
+//	  losses uint32
+//
+// key 是局面和它左右翻转后局面哈希值中较小的一个，着法按 key 对应的方向保存，
+// 这样左右对称的开局共用同一组记录。
+const (
+	magic   = "GCBK"
+	version = 1
+)
+
+var ErrInvalidBook = errors.New("book: invalid book file")
+
+// Entry 是某个局面下的一个开局库着法，胜负和统计都以走子方为视角
+type Entry struct {
+	Move   chess.Move
+	Weight uint32
+	Wins   uint32
+	Draws  uint32
+	Losses uint32
+}
+
+type Book struct {
+	positions map[uint64][]Entry
+}
+
+type record struct {
+	Key    uint64
+	From   uint8
+	To     uint8
+	Weight uint32
+	Wins   uint32
+	Draws  uint32
+	Losses uint32
+}
+
+func New() *Book {
+	return &Book{positions: map[uint64][]Entry{}}
+}
+
+// Key 返回局面在开局库中的 key，flipped 表示 key 来自左右翻转后的局面
+func Key(b *chess.Board) (key uint64, flipped bool) {
+	hash := b.Hash()
+	flippedHash := b.FlipFiles().Hash()
+	if flippedHash < hash {
+		return flippedHash, true
+	}
+	return hash, false
+}
+
+func flipMove(move chess.Move) chess.Move {
+	return chess.Move{
+		FromSquare: chess.SquareFlipFile(move.FromSquare),
+		ToSquare:   chess.SquareFlipFile(move.ToSquare),
+	}
+}
+
+func Open(path string) (*Book, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(bufio.NewReader(f))
+}
+
+func Read(r io.Reader) (*Book, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil || string(header) != magic {
+		return nil, ErrInvalidBook
+	}
+	var v, count uint32
+	if err := binary.Read(r, binary.LittleEndian, &v); err != nil || v != version {
+		return nil, ErrInvalidBook
+	}
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, ErrInvalidBook
+	}
+	bk := New()
+	for i := uint32(0); i < count; i++ {
+		rec := record{}
+		if err := binary.Read(r, binary.LittleEndian, &rec); err != nil {
+			return nil, ErrInvalidBook
+		}
+		bk.positions[rec.Key] = append(bk.positions[rec.Key], Entry{
+			Move:   chess.Move{FromSquare: rec.From, ToSquare: rec.To},
+			Weight: rec.Weight,
+			Wins:   rec.Wins,
+			Draws:  rec.Draws,
+			Losses: rec.Losses,
+		})
+	}
+	return bk, nil
+}
+
+func (bk *Book) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := bk.Write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (bk *Book) Write(w io.Writer) error {
+	keys := make([]uint64, 0, len(bk.positions))
+	count := 0
+	for key, entries := range bk.positions {
+		keys = append(keys, key)
+		count += len(entries)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, []uint32{version, uint32(count)}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		for _, e := range bk.positions[key] {
+			rec := record{
+				Key:    key,
+				From:   e.Move.FromSquare,
+				To:     e.Move.ToSquare,
+				Weight: e.Weight,
+				Wins:   e.Wins,
+				Draws:  e.Draws,
+				Losses: e.Losses,
+			}
+			if err := binary.Write(w, binary.LittleEndian, &rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Positions 返回开局库中的局面数
+func (bk *Book) Positions() int {
+	return len(bk.positions)
+}
+
+// Add 加入一个着法，move 以 b 的方向给出
+func (bk *Book) Add(b *chess.Board, e Entry) {
+	key, flipped := Key(b)
+	if flipped {
+		e.Move = flipMove(e.Move)
+	}
+	entries := append(bk.positions[key], e)
+	sortEntries(entries)
+	bk.positions[key] = entries
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Weight != entries[j].Weight {
+			return entries[i].Weight > entries[j].Weight
+		}
+		a, b := entries[i].Move, entries[j].Move
+		return a.FromSquare < b.FromSquare || a.FromSquare == b.FromSquare && a.ToSquare < b.ToSquare
+	})
+}
+
+// Moves 返回局面 b 下开局库中的合法着法，按权重降序排列
+func (bk *Book) Moves(b *chess.Board) []Entry {
+	key, flipped := Key(b)
+	var entries []Entry
+	for _, e := range bk.positions[key] {
+		if flipped {
+			e.Move = flipMove(e.Move)
+		}
+		move := e.Move
+		if b.IsLegal(&move) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Pick 按权重随机选择一个着法，开局库中没有这个局面时返回 nil
+func (bk *Book) Pick(b *chess.Board, rng *rand.Rand) *chess.Move {
+	entries := bk.Moves(b)
+	total := 0
+	for _, e := range entries {
+		total += int(e.Weight)
+	}
+	if total == 0 {
+		return nil
+	}
+	n := rng.Intn(total)
+	for _, e := range entries {
+		n -= int(e.Weight)
+		if n < 0 {
+			move := e.Move
+			return &move
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package book
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+)
+
+func parseMoves(t *testing.T, line string) []*chess.Move {
+	var moves []*chess.Move
+	for _, text := range strings.Fields(line) {
+		move, err := chess.ParseMove(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+func buildBook(t *testing.T) *Book {
+	builder := NewBuilder(4)
+	games := []struct {
+		moves  string
+		result chess.Result
+	}{
+		{"h2e2 h9g7 h0g2 i9h9", chess.RedWins},
+		{"h2e2 h9g7 h0g2 i9h9", chess.Draw},
+		{"b2e2 b9c7 b0c2 a9b9", chess.BlackWins},
+		{"c3c4 g6g5", chess.NoResult},
+	}
+	for _, game := range games {
+		if err := builder.AddGame(chess.NewBoard(), parseMoves(t, game.moves), game.result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return builder.Book(1)
+}
+
+func TestBuilder(t *testing.T) {
+	bk := buildBook(t)
+	entries := bk.Moves(chess.NewBoard())
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 moves in starting position, got %+v", entries)
+	}
+	first := entries[0]
+	if first.Move.String() != "h2e2" || first.Wins != 1 || first.Draws != 1 || first.Weight != 3 {
+		t.Errorf("unexpected first entry %+v", first)
+	}
+	for _, e := range entries {
+		if e.Move.String() == "b2e2" && e.Weight != 0 {
+			t.Errorf("losing move should have no weight: %+v", e)
+		}
+	}
+	if err := NewBuilder(10).AddGame(chess.NewBoard(), parseMoves(t, "h2e2 h2e2"), chess.Draw); err == nil {
+		t.Error("expected error for illegal move")
+	}
+}
+
+func TestMirroredPositions(t *testing.T) {
+	bk := buildBook(t)
+	// h2e2 之后的局面和 b2e2 之后的局面左右对称，应该共用记录
+	left, right := chess.NewBoard(), chess.NewBoard()
+	left.Push(parseMoves(t, "h2e2")[0])
+	right.Push(parseMoves(t, "b2e2")[0])
+	leftKey, _ := Key(left)
+	rightKey, _ := Key(right)
+	if leftKey != rightKey {
+		t.Fatal("mirrored positions have different keys")
+	}
+	leftMoves, rightMoves := bk.Moves(left), bk.Moves(right)
+	// h9g7 和 b9c7 互为镜像，三局棋合并到同一条记录
+	if len(leftMoves) != 1 || len(rightMoves) != 1 || leftMoves[0].Wins+leftMoves[0].Draws+leftMoves[0].Losses != 3 {
+		t.Fatalf("expected shared entries, got %+v and %+v", leftMoves, rightMoves)
+	}
+	for i := range leftMoves {
+		if leftMoves[i].Move != flipMove(rightMoves[i].Move) {
+			t.Errorf("entry %d not mirrored: %v vs %v", i, leftMoves[i].Move, rightMoves[i].Move)
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	bk := buildBook(t)
+	buf := &bytes.Buffer{}
+	if err := bk.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Positions() != bk.Positions() {
+		t.Errorf("got %d positions, want %d", read.Positions(), bk.Positions())
+	}
+	b := chess.NewBoard()
+	if len(read.Moves(b)) != len(bk.Moves(b)) {
+		t.Error("moves differ after round trip")
+	}
+	if _, err := Read(strings.NewReader("nope")); err != ErrInvalidBook {
+		t.Errorf("got %v, want ErrInvalidBook", err)
+	}
+}
+
+func TestPick(t *testing.T) {
+	bk := buildBook(t)
+	rng := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[bk.Pick(chess.NewBoard(), rng).String()]++
+	}
+	if counts["b2e2"] != 0 || counts["h2e2"] < counts["c3c4"] {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+	b, _ := chess.NewBoardFromFen("4k4/9/9/9/9/9/9/9/9/4K4 w - - 0 1")
+	if move := bk.Pick(b, rng); move != nil {
+		t.Errorf("expected no book move, got %v", move)
+	}
+}
@@ -0,0 +1,92 @@
+package book
+
+import (
+	"fmt"
+
+	"github.com/clysto/gochess/chess"
+)
+
+type moveStats struct {
+	games  uint32
+	wins   uint32
+	draws  uint32
+	losses uint32
+}
+
+// Builder 统计对局中每个局面下着法的出现次数和结果，生成开局库
+type Builder struct {
+	// MaxPly 是每局棋最多统计的步数
+	MaxPly int
+
+	stats map[uint64]map[chess.Move]*moveStats
+}
+
+func NewBuilder(maxPly int) *Builder {
+	return &Builder{
+		MaxPly: maxPly,
+		stats:  map[uint64]map[chess.Move]*moveStats{},
+	}
+}
+
+// AddGame 从局面 start 开始依次走 moves，记录前 MaxPly 步，遇到非法着法返回错误
+func (bd *Builder) AddGame(start *chess.Board, moves []*chess.Move, result chess.Result) error {
+	b := start.Copy()
+	winner, decisive := result.Winner()
+	for ply, move := range moves {
+		if ply >= bd.MaxPly {
+			break
+		}
+		if !b.IsLegal(move) {
+			return fmt.Errorf("book: illegal move %v at ply %d", move, ply+1)
+		}
+		key, flipped := Key(b)
+		m := *move
+		if flipped {
+			m = flipMove(m)
+		}
+		if bd.stats[key] == nil {
+			bd.stats[key] = map[chess.Move]*moveStats{}
+		}
+		s := bd.stats[key][m]
+		if s == nil {
+			s = &moveStats{}
+			bd.stats[key][m] = s
+		}
+		s.games++
+		switch {
+		case decisive && winner == b.Turn():
+			s.wins++
+		case decisive:
+			s.losses++
+		case result == chess.Draw:
+			s.draws++
+		}
+		b.Push(move)
+	}
+	return nil
+}
+
+// Book 生成开局库，只保留至少出现 minGames 次的着法。
+// 权重为胜局 2 分、和局 1 分，结果未知的对局按出现次数计 1 分，只输不赢的着法权重为 0
+func (bd *Builder) Book(minGames int) *Book {
+	bk := New()
+	for key, moves := range bd.stats {
+		for move, s := range moves {
+			if int(s.games) < minGames {
+				continue
+			}
+			unknown := s.games - s.wins - s.draws - s.losses
+			bk.positions[key] = append(bk.positions[key], Entry{
+				Move:   move,
+				Weight: 2*s.wins + s.draws + unknown,
+				Wins:   s.wins,
+				Draws:  s.draws,
+				Losses: s.losses,
+			})
+		}
+		if entries := bk.positions[key]; len(entries) > 0 {
+			sortEntries(entries)
+		}
+	}
+	return bk
+}
@@ -1,6 +1,9 @@
 package chess
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/holiman/uint256"
 )
 
@@ -15,6 +18,18 @@ type Board struct {
 	occupied      *uint256.Int
 	occupiedColor map[bool]*uint256.Int
 	turn          bool
+
+	hash           uint64
+	halfmoveClock  int
+	fullmoveNumber int
+	stack          []boardState
+}
+
+type boardState struct {
+	move          Move
+	captured      uint8
+	hash          uint64
+	halfmoveClock int
 }
 
 type Piece struct {
@@ -27,6 +42,32 @@ type Move struct {
 	ToSquare   uint8
 }
 
+// NullMove 表示空着，只在搜索中使用
+var NullMove = Move{}
+
+func ParseMove(s string) (*Move, error) {
+	if len(s) != 4 {
+		return nil, fmt.Errorf("chess: invalid move %q", s)
+	}
+	from, err := ParseSquare(strings.ToLower(s[:2]))
+	if err != nil {
+		return nil, err
+	}
+	to, err := ParseSquare(strings.ToLower(s[2:]))
+	if err != nil {
+		return nil, err
+	}
+	return &Move{FromSquare: from, ToSquare: to}, nil
+}
+
+// String 返回 ICCS 坐标格式，例如 h2e2
+func (m Move) String() string {
+	if m == NullMove {
+		return "0000"
+	}
+	return SquareName(m.FromSquare) + SquareName(m.ToSquare)
+}
+
 var (
 	BbCorners    = *Or(&BbA0, &BbI0, &BbA9, &BbI9)
 	BbRedPawns   = *Or(&BbA3, &BbC3, &BbE3, &BbG3, &BbI3)
@@ -55,6 +96,11 @@ func SquareMirror(square uint8) uint8 {
 	return square ^ 0xf0
 }
 
+// SquareFlipFile 返回左右对称的格子
+func SquareFlipFile(square uint8) uint8 {
+	return square&0xf0 | uint8(14-SquareFile(square))
+}
+
 func SquareFile(square uint8) int {
 	return int(square & 0xf)
 }
@@ -92,18 +138,143 @@ func NewBoard() *Board {
 	b.advisors = Or(&BbD0, &BbF0, &BbD9, &BbF9)
 	b.kings = Or(&BbE0, &BbE9)
 	b.occupiedColor = map[bool]*uint256.Int{
-		Red:   Or(&Rank0, &BbB2, &BbH2, &BbRedPawns),
-		Black: Or(&Rank9, &BbB7, &BbH7, &BbBlackPawns),
+		Red:   And(Or(&Rank0, &BbB2, &BbH2, &BbRedPawns), &BbInBoard),
+		Black: And(Or(&Rank9, &BbB7, &BbH7, &BbBlackPawns), &BbInBoard),
 	}
 	b.occupied = Or(b.occupiedColor[Red], b.occupiedColor[Black])
 	b.turn = Red
+	b.fullmoveNumber = 1
+	for _, sq := range ScanReversed(b.occupied.Clone()) {
+		piece := b.PieceAt(sq)
+		b.hash ^= zobristPieces[colorIndex(piece.Color)][piece.PieceType][sq]
+	}
 	return &b
 }
 
+func NewEmptyBoard() *Board {
+	return &Board{
+		pawns:    Zero(),
+		knights:  Zero(),
+		bishops:  Zero(),
+		rooks:    Zero(),
+		cannons:  Zero(),
+		advisors: Zero(),
+		kings:    Zero(),
+		occupied: Zero(),
+		occupiedColor: map[bool]*uint256.Int{
+			Red:   Zero(),
+			Black: Zero(),
+		},
+		turn:           Red,
+		fullmoveNumber: 1,
+	}
+}
+
+func (b *Board) Copy() *Board {
+	c := *b
+	c.pawns = b.pawns.Clone()
+	c.knights = b.knights.Clone()
+	c.bishops = b.bishops.Clone()
+	c.rooks = b.rooks.Clone()
+	c.cannons = b.cannons.Clone()
+	c.advisors = b.advisors.Clone()
+	c.kings = b.kings.Clone()
+	c.occupied = b.occupied.Clone()
+	c.occupiedColor = map[bool]*uint256.Int{
+		Red:   b.occupiedColor[Red].Clone(),
+		Black: b.occupiedColor[Black].Clone(),
+	}
+	c.stack = append([]boardState(nil), b.stack...)
+	return &c
+}
+
+// FlipFiles 返回左右翻转后的局面，不包括着法历史
+func (b *Board) FlipFiles() *Board {
+	c := NewEmptyBoard()
+	for _, sq := range ScanReversed(b.occupied.Clone()) {
+		piece := b.PieceAt(sq)
+		c.setPieceAt(SquareFlipFile(sq), piece.PieceType, piece.Color)
+	}
+	c.turn = b.turn
+	if !c.turn {
+		c.hash ^= zobristTurn
+	}
+	c.halfmoveClock = b.halfmoveClock
+	c.fullmoveNumber = b.fullmoveNumber
+	return c
+}
+
 func (b *Board) Turn() bool {
 	return b.turn
 }
 
+// Pieces 返回某一方某种棋子的位棋盘
+func (b *Board) Pieces(pieceType uint8, color bool) *uint256.Int {
+	var bb *uint256.Int
+	switch pieceType {
+	case Pawn:
+		bb = b.pawns
+	case Knight:
+		bb = b.knights
+	case Bishop:
+		bb = b.bishops
+	case Rook:
+		bb = b.rooks
+	case Cannon:
+		bb = b.cannons
+	case Advisor:
+		bb = b.advisors
+	case King:
+		bb = b.kings
+	default:
+		return Zero()
+	}
+	return And(bb, b.occupiedColor[color])
+}
+
+func (b *Board) Occupied() *uint256.Int {
+	return b.occupied.Clone()
+}
+
+func (b *Board) OccupiedColor(color bool) *uint256.Int {
+	return b.occupiedColor[color].Clone()
+}
+
+// Hash 返回当前局面的 Zobrist 哈希值
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+func (b *Board) HalfmoveClock() int {
+	return b.halfmoveClock
+}
+
+func (b *Board) FullmoveNumber() int {
+	return b.fullmoveNumber
+}
+
+// Ply 返回从初始局面开始已经走过的步数
+func (b *Board) Ply() int {
+	return len(b.stack)
+}
+
+func (b *Board) MoveStack() []*Move {
+	moves := make([]*Move, len(b.stack))
+	for i := range b.stack {
+		move := b.stack[i].move
+		moves[i] = &move
+	}
+	return moves
+}
+
+func (b *Board) Peek() *Move {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	move := b.stack[len(b.stack)-1].move
+	return &move
+}
+
 func (b *Board) PieceAt(sq uint8) *Piece {
 	t := b.PieceTypeAt(sq)
 	if t > 0 {
@@ -210,6 +381,8 @@ func (b *Board) removePieceAt(square uint8) uint8 {
 	default:
 		return 0
 	}
+	color := !And(b.occupiedColor[Red], mask).IsZero()
+	b.hash ^= zobristPieces[colorIndex(color)][pieceType][square]
 	b.occupied.Xor(b.occupied, mask)
 	b.occupiedColor[Red].And(b.occupiedColor[Red], Not(mask))
 	b.occupiedColor[Black].And(b.occupiedColor[Black], Not(mask))
@@ -239,14 +412,95 @@ func (b *Board) setPieceAt(square uint8, pieceType uint8, color bool) {
 		return
 	}
 
+	b.hash ^= zobristPieces[colorIndex(color)][pieceType][square]
 	b.occupied.Xor(b.occupied, mask)
 	b.occupiedColor[color].Xor(b.occupiedColor[color], mask)
 }
 
+// SetPieceAt 在 square 放置棋子，piece 为 nil 时清空该格，不记录到着法历史中
+func (b *Board) SetPieceAt(square uint8, piece *Piece) {
+	if piece == nil {
+		b.removePieceAt(square)
+		return
+	}
+	b.setPieceAt(square, piece.PieceType, piece.Color)
+}
+
+func (b *Board) SetTurn(turn bool) {
+	if b.turn != turn {
+		b.turn = turn
+		b.hash ^= zobristTurn
+	}
+}
+
+// FlipColors 返回上下翻转并交换双方颜色后的局面，不包括着法历史
+func (b *Board) FlipColors() *Board {
+	c := NewEmptyBoard()
+	for _, sq := range ScanReversed(b.occupied.Clone()) {
+		piece := b.PieceAt(sq)
+		c.setPieceAt(SquareMirror(sq), piece.PieceType, !piece.Color)
+	}
+	c.SetTurn(!b.turn)
+	c.halfmoveClock = b.halfmoveClock
+	c.fullmoveNumber = b.fullmoveNumber
+	return c
+}
+
 func (b *Board) Push(move *Move) {
-	pieceType := b.removePieceAt(move.FromSquare)
-	b.setPieceAt(move.ToSquare, pieceType, b.turn)
+	state := boardState{
+		move:          *move,
+		hash:          b.hash,
+		halfmoveClock: b.halfmoveClock,
+	}
+	if *move != NullMove {
+		state.captured = b.PieceTypeAt(move.ToSquare)
+		pieceType := b.removePieceAt(move.FromSquare)
+		b.setPieceAt(move.ToSquare, pieceType, b.turn)
+	}
+	b.stack = append(b.stack, state)
+	if state.captured > 0 {
+		b.halfmoveClock = 0
+	} else {
+		b.halfmoveClock++
+	}
+	if !b.turn {
+		b.fullmoveNumber++
+	}
+	b.turn = !b.turn
+	b.hash ^= zobristTurn
+}
+
+// Pop 撤销最后一步棋并返回该着法
+func (b *Board) Pop() *Move {
+	if len(b.stack) == 0 {
+		return nil
+	}
+	state := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
 	b.turn = !b.turn
+	if !b.turn {
+		b.fullmoveNumber--
+	}
+	move := state.move
+	if move != NullMove {
+		pieceType := b.removePieceAt(move.ToSquare)
+		b.setPieceAt(move.FromSquare, pieceType, b.turn)
+		if state.captured > 0 {
+			b.setPieceAt(move.ToSquare, state.captured, !b.turn)
+		}
+	}
+	b.hash = state.hash
+	b.halfmoveClock = state.halfmoveClock
+	return &move
+}
+
+// PushNull 走一步空着，只改变走子方
+func (b *Board) PushNull() {
+	b.Push(&NullMove)
+}
+
+func (b *Board) IsCapture(move *Move) bool {
+	return !And(b.occupiedColor[!b.turn], &BbSquares[move.ToSquare]).IsZero()
 }
 
 func (b *Board) IsPseudoLegal(move *Move) bool {
@@ -270,3 +524,135 @@ func (b *Board) IsPseudoLegal(move *Move) bool {
 
 	return !And(b.AttacksMask(move.FromSquare), toMask).IsZero()
 }
+
+func (b *Board) King(color bool) uint8 {
+	kings := And(b.kings, b.occupiedColor[color])
+	if kings.IsZero() {
+		return 0
+	}
+	return uint8(Msb(kings))
+}
+
+// IsAttackedBy 判断 color 一方是否攻击 square 格子（不包括将帅对脸）
+func (b *Board) IsAttackedBy(color bool, square uint8) bool {
+	them := b.occupiedColor[color]
+	fileAttacks := MovesTable.BbFileAttacks[square][*And(&MovesTable.BbFileMasks[square], b.occupied)]
+	rankAttacks := MovesTable.BbRankAttacks[square][*And(&MovesTable.BbRankMasks[square], b.occupied)]
+	if !And(Or(&fileAttacks, &rankAttacks), b.rooks, them).IsZero() {
+		return true
+	}
+	cannonFile := MovesTable.BbCannonFileAttacks[square][*And(&MovesTable.BbCannonFileMasks[square], b.occupied)]
+	cannonRank := MovesTable.BbCannonRankAttacks[square][*And(&MovesTable.BbCannonRankMasks[square], b.occupied)]
+	if !And(Or(&cannonFile, &cannonRank), b.cannons, them).IsZero() {
+		return true
+	}
+	if !And(&MovesTable.BbKingAttacks[square], b.kings, them).IsZero() {
+		return true
+	}
+	if !And(&MovesTable.BbAdvisorAttacks[square], b.advisors, them).IsZero() {
+		return true
+	}
+	bishopAttacks := MovesTable.BbBishopAttacks[square][*And(&MovesTable.BbBishopMasks[square], b.occupied)]
+	if !And(&bishopAttacks, b.bishops, them).IsZero() {
+		return true
+	}
+	target := &BbSquares[square]
+	for _, sq := range ScanReversed(And(Or(b.knights, b.pawns), them)) {
+		if SquareDistance(sq, square) <= 2 && !And(b.AttacksMask(sq), target).IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// KingsFacing 判断将帅是否照面
+func (b *Board) KingsFacing() bool {
+	red, black := b.King(Red), b.King(Black)
+	if red == 0 || black == 0 || SquareFile(red) != SquareFile(black) {
+		return false
+	}
+	attacks := MovesTable.BbFileAttacks[red][*And(&MovesTable.BbFileMasks[red], b.occupied)]
+	return !And(&attacks, &BbSquares[black]).IsZero()
+}
+
+func (b *Board) IsCheck() bool {
+	king := b.King(b.turn)
+	return king != 0 && (b.IsAttackedBy(!b.turn, king) || b.KingsFacing())
+}
+
+// wasLegal 判断上一步棋走完后，走棋一方的将帅是否安全
+func (b *Board) wasLegal() bool {
+	king := b.King(!b.turn)
+	return king != 0 && !b.IsAttackedBy(b.turn, king) && !b.KingsFacing()
+}
+
+func (b *Board) IsLegal(move *Move) bool {
+	if !b.IsPseudoLegal(move) {
+		return false
+	}
+	b.Push(move)
+	legal := b.wasLegal()
+	b.Pop()
+	return legal
+}
+
+func (b *Board) LegalMoves() []*Move {
+	moves := b.PseudoLegalMoves(&BbInBoard, &BbInBoard)
+	legal := moves[:0]
+	for _, move := range moves {
+		b.Push(move)
+		if b.wasLegal() {
+			legal = append(legal, move)
+		}
+		b.Pop()
+	}
+	return legal
+}
+
+// IsCheckmate 判断是否无棋可走，象棋中困毙同样判负
+func (b *Board) IsCheckmate() bool {
+	return len(b.LegalMoves()) == 0
+}
+
+// RepetitionCount 返回当前局面在历史中出现的次数（包括当前局面）
+func (b *Board) RepetitionCount() int {
+	count := 1
+	// 吃子之后的局面不可能和之前的局面重复
+	for i := len(b.stack) - 1; i >= 0 && i >= len(b.stack)-b.halfmoveClock; i-- {
+		if b.stack[i].hash == b.hash {
+			count++
+		}
+	}
+	return count
+}
+
+// PerpetualChecker 在当前局面和之前的局面重复时检查从上一次出现到现在的循环，
+// 只有一方在循环中每一步都将军时返回这一方，ok 为 false 时不是长将
+func (b *Board) PerpetualChecker() (color bool, ok bool) {
+	n := 0
+	for i := len(b.stack) - 1; i >= 0 && i >= len(b.stack)-b.halfmoveClock; i-- {
+		if b.stack[i].hash == b.hash {
+			n = len(b.stack) - i
+			break
+		}
+	}
+	if n == 0 {
+		return false, false
+	}
+	// 退回到上一次出现的局面，记录双方是否每一步都将军，之后再重新走回来
+	always := map[bool]bool{Red: true, Black: true}
+	moves := make([]*Move, n)
+	for i := n - 1; i >= 0; i-- {
+		if !b.IsCheck() {
+			always[!b.turn] = false
+		}
+		moves[i] = b.Pop()
+	}
+	for _, move := range moves {
+		b.Push(move)
+	}
+	if always[Red] == always[Black] {
+		return false, false
+	}
+	return always[Red], true
+}
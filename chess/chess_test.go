@@ -1,7 +1,10 @@
 package chess
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -31,3 +34,320 @@ func TestPseudoLegalMoves(t *testing.T) {
 		fmt.Println(move)
 	}
 }
+
+func perft(b *Board, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	nodes := 0
+	for _, move := range b.LegalMoves() {
+		b.Push(move)
+		nodes += perft(b, depth-1)
+		b.Pop()
+	}
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	b := NewBoard()
+	hash := b.Hash()
+	for depth, expected := range []int{1, 44, 1920, 79666} {
+		if n := perft(b, depth); n != expected {
+			t.Errorf("perft(%d) = %d, want %d", depth, n, expected)
+		}
+	}
+	if b.Hash() != hash || b.Fen() != StartingFen {
+		t.Errorf("board changed after perft: %s", b.Fen())
+	}
+}
+
+func TestFen(t *testing.T) {
+	b, err := NewBoardFromFen(StartingFen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() != NewBoard().Hash() {
+		t.Error("hash of parsed starting position differs from NewBoard")
+	}
+	fen := "4kab2/4a4/4b4/p3N3p/2n6/9/P7P/4B4/4A4/2BAK4 b - - 3 40"
+	b, err = NewBoardFromFen(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Fen() != fen {
+		t.Errorf("Fen() = %q, want %q", b.Fen(), fen)
+	}
+	if _, err := NewBoardFromFen("rnbakabnr/9/1c5c1 w"); err == nil {
+		t.Error("expected error for truncated fen")
+	}
+}
+
+func TestMoveString(t *testing.T) {
+	move, err := ParseMove("h2e2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move.FromSquare != H2 || move.ToSquare != E2 || move.String() != "h2e2" {
+		t.Errorf("unexpected move %v", move)
+	}
+	b := NewBoard()
+	b.Push(move)
+	if b.Fen() != "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1" {
+		t.Errorf("unexpected fen %s", b.Fen())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// 将帅对脸时不能移开中间的棋子
+	b, _ := NewBoardFromFen("4k4/9/9/9/9/9/9/9/4R4/4K4 b - - 0 1")
+	if !b.IsCheck() {
+		t.Error("expected check from rook")
+	}
+	b, _ = NewBoardFromFen("4k4/9/9/9/9/9/9/9/4C4/4K4 w - - 0 1")
+	for _, move := range b.LegalMoves() {
+		if move.FromSquare == E1 && SquareFile(move.ToSquare) != SquareFile(E1) {
+			t.Errorf("illegal move %v leaves kings facing", move)
+		}
+	}
+	b, _ = NewBoardFromFen("3k5/9/9/9/9/9/9/9/9/4K4 w - - 0 1")
+	for _, move := range b.LegalMoves() {
+		if move.ToSquare == D0 {
+			t.Errorf("king move %v into facing kings", move)
+		}
+	}
+}
+
+func TestPerpetualChecker(t *testing.T) {
+	play := func(fen string, moves ...string) *Board {
+		b, _ := NewBoardFromFen(fen)
+		for _, s := range moves {
+			move, _ := ParseMove(s)
+			b.Push(move)
+		}
+		return b
+	}
+	// 红车每一步都将军
+	b := play("4k4/9/9/9/9/9/9/9/9/R2K5 w - - 0 1", "a0a9", "e9e8", "a9a8", "e8e9", "a8a9", "e9e8")
+	fen := b.Fen()
+	if color, ok := b.PerpetualChecker(); !ok || color != Red {
+		t.Errorf("perpetual checker %v %v, want red", color, ok)
+	}
+	if b.Fen() != fen || b.Ply() != 6 {
+		t.Errorf("board changed to %s", b.Fen())
+	}
+	// 重复局面中没有将军
+	b = play("4k4/9/9/9/9/9/9/9/9/R2K5 w - - 0 1", "a0a1", "e9e8", "a1a0", "e8e9")
+	if _, ok := b.PerpetualChecker(); ok || b.RepetitionCount() != 2 {
+		t.Error("repetition without checks is not a perpetual check")
+	}
+	if _, ok := play("4k4/9/9/9/9/9/9/9/9/R2K5 w - - 0 1", "a0a9").PerpetualChecker(); ok {
+		t.Error("no repetition")
+	}
+}
+
+func TestFlipFiles(t *testing.T) {
+	b, _ := NewBoardFromFen("3k5/9/9/9/9/9/9/9/R8/4K4 b - - 0 1")
+	flipped := b.FlipFiles()
+	if flipped.Fen() != "5k3/9/9/9/9/9/9/9/8R/4K4 b - - 0 1" {
+		t.Errorf("unexpected flipped fen %s", flipped.Fen())
+	}
+	if NewBoard().FlipFiles().Hash() != NewBoard().Hash() {
+		t.Error("starting position should be symmetric")
+	}
+}
+
+func TestMaterial(t *testing.T) {
+	b := NewBoard()
+	m := b.Material(Red)
+	if m.Rooks != 2 || m.Pawns != 5 || m.Attackers() != 11 {
+		t.Errorf("unexpected material %+v", m)
+	}
+	if sig := b.MaterialSignature(); sig != "KRRNNCCPPPPPAABBvKRRNNCCPPPPPAABB" {
+		t.Errorf("got signature %s", sig)
+	}
+	if b.IsInsufficientMaterial() || b.IsDeadDraw() || b.Result() != NoResult {
+		t.Error("starting position is not a draw")
+	}
+}
+
+func TestDeadDraw(t *testing.T) {
+	tests := []struct {
+		fen          string
+		insufficient bool
+		dead         bool
+	}{
+		{"3akab2/9/9/9/9/9/9/9/9/3AKA3 w", true, true},
+		{"4k4/9/9/9/9/9/9/9/9/3KC4 w", false, true},
+		{"3ak4/9/9/9/9/9/9/9/9/3KC4 w", false, true},
+		// 双士可以做炮架
+		{"3akab2/9/9/9/9/9/9/9/9/3KC4 w", false, false},
+		{"3k1P3/9/9/9/9/9/9/9/9/4K4 w", false, true},
+		{"3k5/9/9/4P4/9/9/9/9/9/4K4 w", false, false},
+		{"3k5/9/9/9/9/9/9/9/9/4K1p2 b", false, true},
+		{"4k4/9/9/9/9/9/9/9/9/3KC2c1 w", false, false},
+	}
+	for _, test := range tests {
+		b, err := NewBoardFromFen(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.IsInsufficientMaterial() != test.insufficient || b.IsDeadDraw() != test.dead {
+			t.Errorf("%s: insufficient %v dead %v", test.fen, b.IsInsufficientMaterial(), b.IsDeadDraw())
+		}
+		if test.dead && b.Result() != Draw {
+			t.Errorf("%s: got result %v", test.fen, b.Result())
+		}
+	}
+}
+
+func TestNotation(t *testing.T) {
+	tests := []struct {
+		fen     string
+		move    string
+		wxf     string
+		chinese string
+	}{
+		{StartingFen, "h2e2", "C2.5", "炮二平五"},
+		{StartingFen, "h0g2", "H2+3", "马二进三"},
+		{StartingFen, "g0e2", "E3+5", "相三进五"},
+		{StartingFen, "f0e1", "A4+5", "仕四进五"},
+		{"rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR b", "h9g7", "H8+7", "马８进７"},
+		{"rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR b", "i6i5", "P9+1", "卒９进１"},
+		{"4k4/9/9/9/9/4R4/9/4R4/9/3K5 w", "e4e8", "R++4", "前车进四"},
+		{"4k4/9/9/9/9/4R4/9/4R4/9/3K5 w", "e2e0", "R--2", "后车退二"},
+		{"5k3/9/4P4/4P4/4P4/9/9/9/9/3K5 w", "e6f6", "Pb.4", "中兵平四"},
+		{"3k5/9/9/9/9/9/9/9/9/4K4 b", "d9d8", "K4+1", "将４进１"},
+		{"3k5/9/9/2P3P2/2P3P2/9/9/9/9/4K4 w", "c6c7", "P+7+1", "前兵七进一"},
+		{"3k5/9/9/2P3P2/2P3P2/9/9/9/9/4K4 w", "g5h5", "P-3.2", "后兵三平二"},
+		{"4k4/9/9/9/9/2p3p2/2p3p2/9/9/3K5 b", "c3c2", "P+3+1", "前卒３进１"},
+	}
+	for _, test := range tests {
+		b, err := NewBoardFromFen(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		move, _ := ParseMove(test.move)
+		if wxf, chinese := b.WXF(move), b.Chinese(move); wxf != test.wxf || chinese != test.chinese {
+			t.Errorf("%s %s: got %s %s, want %s %s", test.fen, test.move, wxf, chinese, test.wxf, test.chinese)
+		}
+		if parsed, err := b.ParseWXF(test.wxf); err != nil || *parsed != *move {
+			t.Errorf("ParseWXF(%s) = %v, %v", test.wxf, parsed, err)
+		}
+		if parsed, err := b.ParseChinese(test.chinese); err != nil || *parsed != *move {
+			t.Errorf("ParseChinese(%s) = %v, %v", test.chinese, parsed, err)
+		}
+	}
+
+	b := NewBoard()
+	for _, s := range []string{"c2.5", "+C.5", "C2=5"} {
+		if _, err := b.ParseWXF(s); (err == nil) != (s != "+C.5") {
+			t.Errorf("ParseWXF(%s): %v", s, err)
+		}
+	}
+	for _, s := range []string{"炮2平5", "砲二平五", "炮二进五"} {
+		if _, err := b.ParseChinese(s); (err == nil) != (s != "炮二进五") {
+			t.Errorf("ParseChinese(%s): %v", s, err)
+		}
+	}
+	// ParseMove 根据写法判断格式
+	for _, s := range []string{"h2e2", "H2-E2", "C2.5", "炮二平五", " h2e2 "} {
+		if move, err := b.ParseMove(s); err != nil || move.String() != "h2e2" {
+			t.Errorf("ParseMove(%q) = %v, %v", s, move, err)
+		}
+	}
+	for _, s := range []string{"a0a5", "C2+5", "", "炮二进五"} {
+		if _, err := b.ParseMove(s); err == nil {
+			t.Errorf("ParseMove(%q): expected error", s)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	b, err := NewBoardFromFen("3k5/4a4/9/9/9/9/9/4C4/9/4K4 w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `9 . . . k + + . . .
+8 . . . + a + . . .
+7 . . . + + + . . .
+6 . . . . . . . . .
+5 . . . . . . . . .
+  ~~~~~~~~~~~~~~~~~
+4 . . . . . . . . .
+3 . . . . . . . . .
+2 . . . + C + . . .
+1 . . . + + + . . .
+0 . . . + K + . . .
+  a b c d e f g h i
+3k5/4a4/9/9/9/9/9/4C4/9/4K4 w - - 0 1`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	want = `．．．＋帥＋．．．
+．．．＋＋＋．．．
+．．．＋炮＋．．．
+．．．．．．．．．
+．．．．．．．．．
+～～～～楚河～漢界～～～～
+．．．．．．．．．
+．．．．．．．．．
+．．．＋＋＋．．．
+．．．＋士＋．．．
+．．．＋＋将．．．
+`
+	got := b.Text(TextOptions{Style: TextChinese, Flip: true})
+	if got = strings.Replace(got, " ", "", -1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	// 没有颜色时双方的炮也不同
+	b, _ = NewBoardFromFen("3k5/9/1c7/9/9/9/9/7C1/9/4K4 w")
+	plain := b.Text(TextOptions{Style: TextChinese})
+	if !strings.Contains(plain, "砲") || !strings.Contains(plain, "炮") {
+		t.Errorf("cannons not distinguished in\n%s", plain)
+	}
+	colored := b.Text(TextOptions{Style: TextChinese, Color: true})
+	if !strings.Contains(colored, ansiRed+"帥"+ansiReset) || !strings.Contains(colored, ansiBlack+"将"+ansiReset) ||
+		!strings.Contains(colored, ansiBlack+"砲"+ansiReset) {
+		t.Errorf("missing colours in\n%s", colored)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type record struct {
+		Board  *Board          `json:"board"`
+		Moves  []Move          `json:"moves"`
+		Pieces map[Move]*Piece `json:"pieces"`
+		Result Result          `json:"result"`
+	}
+	b := NewBoard()
+	b.Push(&Move{FromSquare: H2, ToSquare: E2})
+	r := record{
+		Board:  b,
+		Moves:  []Move{{FromSquare: H9, ToSquare: G7}, NullMove},
+		Pieces: map[Move]*Piece{{FromSquare: H2, ToSquare: E2}: {Color: Red, PieceType: Cannon}},
+		Result: Draw,
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"board":"rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1",` +
+		`"moves":["h9g7","0000"],"pieces":{"h2e2":"C"},"result":"1/2-1/2"}`
+	if string(data) != want {
+		t.Fatalf("got %s\nwant %s", data, want)
+	}
+	var decoded record
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Board.Fen() != b.Fen() || decoded.Board.Hash() != b.Hash() ||
+		!reflect.DeepEqual(decoded.Moves, r.Moves) || !reflect.DeepEqual(decoded.Pieces, r.Pieces) || decoded.Result != Draw {
+		t.Errorf("round trip changed %+v", decoded)
+	}
+	for _, invalid := range []string{`{"board":"9/9 w"}`, `{"moves":["h2"]}`, `{"pieces":{"h2e2":"x"}}`, `{"result":"2-0"}`} {
+		if err := json.Unmarshal([]byte(invalid), &decoded); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const StartingFen = "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR w - - 0 1"
+
+var ErrInvalidFen = errors.New("chess: invalid fen")
+
+var pieceSymbols = [8]byte{0, 'p', 'c', 'r', 'n', 'b', 'a', 'k'}
+
+func PieceSymbol(piece *Piece) byte {
+	s := pieceSymbols[piece.PieceType]
+	if piece.Color {
+		return s - 'a' + 'A'
+	}
+	return s
+}
+
+func ParsePieceSymbol(s byte) *Piece {
+	color := s >= 'A' && s <= 'Z'
+	if color {
+		s = s - 'A' + 'a'
+	}
+	// 兼容部分软件使用的 e(象) 和 h(马)
+	switch s {
+	case 'e':
+		s = 'b'
+	case 'h':
+		s = 'n'
+	}
+	for t, symbol := range pieceSymbols {
+		if t > 0 && symbol == s {
+			return &Piece{Color: color, PieceType: uint8(t)}
+		}
+	}
+	return nil
+}
+
+func Square(file int, rank int) uint8 {
+	return uint8((rank+3)<<4 | (file + 3))
+}
+
+func SquareName(square uint8) string {
+	return string([]byte{
+		byte('a' + SquareFile(square) - 3),
+		byte('0' + SquareRank(square) - 3),
+	})
+}
+
+func ParseSquare(name string) (uint8, error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] > 'i' || name[1] < '0' || name[1] > '9' {
+		return 0, fmt.Errorf("chess: invalid square %q", name)
+	}
+	return Square(int(name[0]-'a'), int(name[1]-'0')), nil
+}
+
+func NewBoardFromFen(fen string) (*Board, error) {
+	b := NewEmptyBoard()
+	fields := strings.Fields(fen)
+	if len(fields) == 0 {
+		return nil, ErrInvalidFen
+	}
+	rows := strings.Split(fields[0], "/")
+	if len(rows) != 10 {
+		return nil, ErrInvalidFen
+	}
+	for i, row := range rows {
+		rank := 9 - i
+		file := 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '9' {
+				file += int(c - '0')
+				continue
+			}
+			piece := ParsePieceSymbol(c)
+			if piece == nil || file > 8 {
+				return nil, ErrInvalidFen
+			}
+			b.setPieceAt(Square(file, rank), piece.PieceType, piece.Color)
+			file++
+		}
+		if file != 9 {
+			return nil, ErrInvalidFen
+		}
+	}
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "w", "r":
+			b.turn = Red
+		case "b":
+			b.turn = Black
+		default:
+			return nil, ErrInvalidFen
+		}
+	}
+	if !b.turn {
+		b.hash ^= zobristTurn
+	}
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, ErrInvalidFen
+		}
+		b.halfmoveClock = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, ErrInvalidFen
+		}
+		b.fullmoveNumber = n
+	}
+	return b, nil
+}
+
+func (b *Board) BoardFen() string {
+	builder := strings.Builder{}
+	for rank := 9; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 9; file++ {
+			piece := b.PieceAt(Square(file, rank))
+			if piece == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				builder.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			builder.WriteByte(PieceSymbol(piece))
+		}
+		if empty > 0 {
+			builder.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			builder.WriteByte('/')
+		}
+	}
+	return builder.String()
+}
+
+func (b *Board) Fen() string {
+	turn := "w"
+	if !b.turn {
+		turn = "b"
+	}
+	return fmt.Sprintf("%s %s - - %d %d", b.BoardFen(), turn, b.halfmoveClock, b.fullmoveNumber)
+}
@@ -0,0 +1,67 @@
+package chess
+
+import (
+	"fmt"
+)
+
+// 棋盘、着法、棋子和结果都编码为字符串，在 JSON 中分别是 FEN、ICCS 坐标、FEN 中的字母和 PGN 的结果记号
+
+// MarshalText 返回局面的 FEN，着法历史不会被保存
+func (b *Board) MarshalText() ([]byte, error) {
+	return []byte(b.Fen()), nil
+}
+
+func (b *Board) UnmarshalText(text []byte) error {
+	c, err := NewBoardFromFen(string(text))
+	if err != nil {
+		return err
+	}
+	*b = *c
+	return nil
+}
+
+func (m Move) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Move) UnmarshalText(text []byte) error {
+	if string(text) == NullMove.String() {
+		*m = NullMove
+		return nil
+	}
+	move, err := ParseMove(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *move
+	return nil
+}
+
+func (p Piece) MarshalText() ([]byte, error) {
+	if p.PieceType < Pawn || p.PieceType > King {
+		return nil, fmt.Errorf("chess: invalid piece type %d", p.PieceType)
+	}
+	return []byte{PieceSymbol(&p)}, nil
+}
+
+func (p *Piece) UnmarshalText(text []byte) error {
+	if len(text) != 1 || ParsePieceSymbol(text[0]) == nil {
+		return fmt.Errorf("chess: invalid piece %q", text)
+	}
+	*p = *ParsePieceSymbol(text[0])
+	return nil
+}
+
+func (r Result) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText 解析结果记号，* 表示没有结果
+func (r *Result) UnmarshalText(text []byte) error {
+	result := ParseResult(string(text))
+	if result == NoResult && string(text) != "*" {
+		return fmt.Errorf("chess: invalid result %q", text)
+	}
+	*r = result
+	return nil
+}
@@ -0,0 +1,95 @@
+package chess
+
+import (
+	"math/bits"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// Material 是一方除将帅以外的子力数量
+type Material struct {
+	Rooks    int
+	Knights  int
+	Cannons  int
+	Pawns    int
+	Advisors int
+	Bishops  int
+}
+
+func popCount(bb *uint256.Int) int {
+	n := 0
+	for _, word := range bb {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+func (b *Board) Material(color bool) Material {
+	return Material{
+		Rooks:    popCount(b.Pieces(Rook, color)),
+		Knights:  popCount(b.Pieces(Knight, color)),
+		Cannons:  popCount(b.Pieces(Cannon, color)),
+		Pawns:    popCount(b.Pieces(Pawn, color)),
+		Advisors: popCount(b.Pieces(Advisor, color)),
+		Bishops:  popCount(b.Pieces(Bishop, color)),
+	}
+}
+
+// Attackers 返回能过河进攻的棋子（车马炮兵）数量
+func (m Material) Attackers() int {
+	return m.Rooks + m.Knights + m.Cannons + m.Pawns
+}
+
+// String 返回 KRNCPAB 形式的子力签名
+func (m Material) String() string {
+	sb := strings.Builder{}
+	sb.WriteByte('K')
+	for _, p := range []struct {
+		letter string
+		count  int
+	}{{"R", m.Rooks}, {"N", m.Knights}, {"C", m.Cannons}, {"P", m.Pawns}, {"A", m.Advisors}, {"B", m.Bishops}} {
+		sb.WriteString(strings.Repeat(p.letter, p.count))
+	}
+	return sb.String()
+}
+
+// MaterialSignature 返回红方在前的子力签名，例如 KRPvKAABB
+func (b *Board) MaterialSignature() string {
+	return b.Material(Red).String() + "v" + b.Material(Black).String()
+}
+
+// IsInsufficientMaterial 判断双方是否都没有车马炮兵，这时谁都无法将死对方
+func (b *Board) IsInsufficientMaterial() bool {
+	return b.Material(Red).Attackers() == 0 && b.Material(Black).Attackers() == 0
+}
+
+// canWin 判断 color 一方是否还有取胜的可能，只识别确定不能取胜的情况：
+// 没有进攻子力，或者只剩一个炮或一个底兵而对方没有进攻子力且士不超过一个。
+// 单炮和底兵的结论由残局库验证，对方有双士时可以借士做炮架或者自塞将路而被将死
+func (b *Board) canWin(color bool) bool {
+	m, other := b.Material(color), b.Material(!color)
+	if m.Attackers() == 0 {
+		return false
+	}
+	if m.Attackers() > 1 || m.Advisors > 0 || m.Bishops > 0 || other.Attackers() > 0 || other.Advisors > 1 {
+		return true
+	}
+	if m.Cannons == 1 {
+		return false
+	}
+	if m.Pawns == 1 {
+		// 底兵是已经走到对方底线的兵
+		bottom := 12
+		if color == Black {
+			bottom = 3
+		}
+		return SquareRank(uint8(Msb(b.Pieces(Pawn, color)))) != bottom
+	}
+	return true
+}
+
+// IsDeadDraw 判断双方都不可能取胜的局面，包括子力不足和残局知识中的必和局面
+func (b *Board) IsDeadDraw() bool {
+	return !b.canWin(Red) && !b.canWin(Black)
+}
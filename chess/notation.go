@@ -0,0 +1,255 @@
+package chess
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 记谱法中的纵线从走子方的右边开始数，红方用一到九，黑方用 1 到 9
+func fileNumber(color bool, square uint8) int {
+	if color == Red {
+		return 9 - (SquareFile(square) - 3)
+	}
+	return SquareFile(square) - 3 + 1
+}
+
+// advance 返回从 from 到 to 向前走的行数，后退为负数
+func advance(color bool, from uint8, to uint8) int {
+	if color == Red {
+		return SquareRank(to) - SquareRank(from)
+	}
+	return SquareRank(from) - SquareRank(to)
+}
+
+// tandem 返回和 square 上的棋子同一纵线上的同类棋子的个数，以及它从前往后的序号 (从 0 开始)。
+// 仕和相不区分前后，它们的走法总是可以由进退区分
+func (b *Board) tandem(square uint8, piece *Piece) (count int, index int) {
+	if piece.PieceType == Advisor || piece.PieceType == Bishop {
+		return 1, 0
+	}
+	var squares []uint8
+	for _, sq := range ScanReversed(b.Pieces(piece.PieceType, piece.Color)) {
+		if SquareFile(sq) == SquareFile(square) {
+			squares = append(squares, sq)
+		}
+	}
+	sort.Slice(squares, func(i, j int) bool {
+		if piece.Color == Red {
+			return squares[i] > squares[j]
+		}
+		return squares[i] < squares[j]
+	})
+	for i, sq := range squares {
+		if sq == square {
+			index = i
+		}
+	}
+	return len(squares), index
+}
+
+// tandemFiles 返回有两个以上同类棋子的纵线数
+func (b *Board) tandemFiles(piece *Piece) int {
+	counts := map[int]int{}
+	files := 0
+	for _, sq := range ScanReversed(b.Pieces(piece.PieceType, piece.Color)) {
+		counts[SquareFile(sq)]++
+		if counts[SquareFile(sq)] == 2 {
+			files++
+		}
+	}
+	return files
+}
+
+// notation 是着法的记谱要素，WXF 和中文记谱只是用不同的符号表示
+type notation struct {
+	piece *Piece
+	// count 大于 1 时用 index 区分同一纵线上的棋子，否则用起点的纵线 file。
+	// 多条纵线上都有重叠的同类棋子时 (只可能是兵)，withFile 为 true，前后之外还要写出纵线
+	count    int
+	index    int
+	file     int
+	withFile bool
+	// direction 为 1 表示进，-1 表示退，0 表示平
+	direction int
+	// target 是直走的棋子进退的步数，或者终点的纵线
+	target int
+}
+
+func (b *Board) notation(move *Move) (*notation, error) {
+	piece := b.PieceAt(move.FromSquare)
+	if piece == nil {
+		return nil, fmt.Errorf("chess: no piece on %s", SquareName(move.FromSquare))
+	}
+	n := &notation{piece: piece, file: fileNumber(piece.Color, move.FromSquare)}
+	n.count, n.index = b.tandem(move.FromSquare, piece)
+	n.withFile = n.count > 1 && b.tandemFiles(piece) > 1
+	steps := advance(piece.Color, move.FromSquare, move.ToSquare)
+	switch {
+	case steps > 0:
+		n.direction = 1
+	case steps < 0:
+		n.direction = -1
+		steps = -steps
+	}
+	switch piece.PieceType {
+	case Knight, Bishop, Advisor:
+		n.target = fileNumber(piece.Color, move.ToSquare)
+	default:
+		if n.direction == 0 {
+			n.target = fileNumber(piece.Color, move.ToSquare)
+		} else {
+			n.target = steps
+		}
+	}
+	return n, nil
+}
+
+var wxfPieces = [8]byte{0, 'P', 'C', 'R', 'H', 'E', 'A', 'K'}
+
+// WXF 返回着法的 WXF 记谱，例如 C2.5、H8+7。同一纵线上有两个同类棋子时用 + 和 - 代替纵线表示前后，
+// 例如 C+.5，三个以上时从前往后用 a、b、c 等表示。两条纵线上都有重叠的兵时在前后之后加上纵线，例如 P+7+1
+func (b *Board) WXF(move *Move) string {
+	n, err := b.notation(move)
+	if err != nil {
+		return move.String()
+	}
+	s := []byte{wxfPieces[n.piece.PieceType]}
+	switch {
+	case n.count == 2:
+		s = append(s, "+-"[n.index])
+	case n.count > 2:
+		s = append(s, byte('a'+n.index))
+	default:
+		s = append(s, byte('0'+n.file))
+	}
+	if n.withFile {
+		s = append(s, byte('0'+n.file))
+	}
+	s = append(s, ".+-"[(n.direction+3)%3], byte('0'+n.target))
+	return string(s)
+}
+
+var (
+	chinesePieces = map[bool][8]string{
+		Red:   {"", "兵", "炮", "车", "马", "相", "仕", "帅"},
+		Black: {"", "卒", "炮", "车", "马", "象", "士", "将"},
+	}
+	chineseNumbers = map[bool][10]string{
+		Red:   {"", "一", "二", "三", "四", "五", "六", "七", "八", "九"},
+		Black: {"", "１", "２", "３", "４", "５", "６", "７", "８", "９"},
+	}
+	chineseOrders = map[int][]string{
+		2: {"前", "后"},
+		3: {"前", "中", "后"},
+		4: {"一", "二", "三", "四"},
+		5: {"一", "二", "三", "四", "五"},
+	}
+	chineseDirections = [3]string{"平", "进", "退"}
+)
+
+// Chinese 返回着法的中文记谱，例如 炮二平五、马８进７、前车进一。两条纵线上都有重叠的兵时
+// 在兵的后面加上纵线，例如 前兵七进一
+func (b *Board) Chinese(move *Move) string {
+	n, err := b.notation(move)
+	if err != nil {
+		return move.String()
+	}
+	numbers := chineseNumbers[n.piece.Color]
+	piece := chinesePieces[n.piece.Color][n.piece.PieceType]
+	var s string
+	if n.count > 1 {
+		s = chineseOrders[n.count][n.index] + piece
+		if n.withFile {
+			s += numbers[n.file]
+		}
+	} else {
+		s = piece + numbers[n.file]
+	}
+	return s + chineseDirections[(n.direction+3)%3] + numbers[n.target]
+}
+
+// parseNotation 在所有合法着法中寻找记谱和 s 相同的着法，比较前用 canonical 统一写法
+func (b *Board) parseNotation(s string, format func(*Move) string, canonical func(string) string) (*Move, error) {
+	want := canonical(s)
+	var found *Move
+	for _, move := range b.LegalMoves() {
+		if canonical(format(move)) != want {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("chess: ambiguous move %q", s)
+		}
+		found = move
+	}
+	if found == nil {
+		return nil, fmt.Errorf("chess: illegal move %q", s)
+	}
+	return found, nil
+}
+
+func canonicalWXF(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.NewReplacer("B", "E", "N", "H", "=", ".").Replace(s)
+	// 有的软件把前后写在棋子前面，例如 +C.5
+	if len(s) == 4 && (s[0] == '+' || s[0] == '-') {
+		s = s[1:2] + s[:1] + s[2:]
+	}
+	return s
+}
+
+// ParseWXF 解析 WXF 记谱的着法，也接受用 B、N 表示象和马，用 = 表示平
+func (b *Board) ParseWXF(s string) (*Move, error) {
+	return b.parseNotation(s, b.WXF, canonicalWXF)
+}
+
+var chineseReplacer = strings.NewReplacer(
+	"帥", "帅", "將", "帅", "将", "帅",
+	"士", "仕", "象", "相",
+	"俥", "车", "車", "车", "傌", "马", "馬", "马", "砲", "炮", "包", "炮",
+	"卒", "兵",
+	"進", "进", "後", "后",
+	"一", "1", "二", "2", "三", "3", "四", "4", "五", "5", "六", "6", "七", "7", "八", "8", "九", "9",
+	"１", "1", "２", "2", "３", "3", "４", "4", "５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	" ", "", "　", "",
+)
+
+func canonicalChinese(s string) string {
+	return chineseReplacer.Replace(strings.TrimSpace(s))
+}
+
+// ParseChinese 解析中文记谱的着法，接受繁体字和全角、半角数字
+func (b *Board) ParseChinese(s string) (*Move, error) {
+	return b.parseNotation(s, b.Chinese, canonicalChinese)
+}
+
+// isICCS 判断 s 是否是 ICCS 坐标，例如 h2e2 或者 H2-E2
+func isICCS(s string) bool {
+	s = strings.ToLower(strings.Replace(s, "-", "", 1))
+	return len(s) == 4 && s[0] >= 'a' && s[0] <= 'i' && s[1] >= '0' && s[1] <= '9' &&
+		s[2] >= 'a' && s[2] <= 'i' && s[3] >= '0' && s[3] <= '9'
+}
+
+// ParseICCS 解析 ICCS 坐标的着法并检查是否合法，也接受 H2-E2 这样的写法
+func (b *Board) ParseICCS(s string) (*Move, error) {
+	move, err := ParseMove(strings.Replace(strings.TrimSpace(s), "-", "", 1))
+	if err != nil {
+		return nil, err
+	}
+	if !b.IsLegal(move) {
+		return nil, fmt.Errorf("chess: illegal move %q", s)
+	}
+	return move, nil
+}
+
+// ParseMove 解析这个局面下的一步，根据写法判断是 ICCS 坐标、WXF 还是中文记谱
+func (b *Board) ParseMove(s string) (*Move, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case isICCS(s):
+		return b.ParseICCS(s)
+	case s != "" && s[0] < 0x80:
+		return b.ParseWXF(s)
+	}
+	return b.ParseChinese(s)
+}
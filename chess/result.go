@@ -0,0 +1,62 @@
+package chess
+
+// Result 表示一局棋的结果
+type Result int
+
+const (
+	NoResult Result = iota
+	RedWins
+	BlackWins
+	Draw
+)
+
+func (r Result) String() string {
+	switch r {
+	case RedWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	}
+	return "*"
+}
+
+// ParseResult 解析 1-0、0-1、1/2-1/2 等结果记号，无法识别时返回 NoResult
+func ParseResult(s string) Result {
+	switch s {
+	case "1-0":
+		return RedWins
+	case "0-1":
+		return BlackWins
+	case "1/2-1/2", "½-½":
+		return Draw
+	}
+	return NoResult
+}
+
+// Winner 返回获胜的一方，ok 为 false 表示和棋或没有结果
+func (r Result) Winner() (color bool, ok bool) {
+	switch r {
+	case RedWins:
+		return Red, true
+	case BlackWins:
+		return Black, true
+	}
+	return false, false
+}
+
+// Result 根据局面判断对局结果：无棋可走的一方判负，双方都不可能取胜时判和，
+// 其他情况返回 NoResult。重复局面和自然限着由对局的管理者判断
+func (b *Board) Result() Result {
+	if b.IsCheckmate() {
+		if b.turn == Red {
+			return BlackWins
+		}
+		return RedWins
+	}
+	if b.IsDeadDraw() {
+		return Draw
+	}
+	return NoResult
+}
@@ -0,0 +1,136 @@
+package chess
+
+import (
+	"strings"
+)
+
+// TextStyle 是文本棋盘中棋子的写法
+type TextStyle int
+
+const (
+	// TextASCII 使用 FEN 中的字母，红方大写
+	TextASCII TextStyle = iota
+	// TextChinese 使用汉字，红方是繁体的 車馬相仕帥炮兵，黑方是简体的 车马象士将卒，黑炮写作砲，
+	// 没有颜色时也能区分双方
+	TextChinese
+)
+
+var textGlyphs = map[bool][8]string{
+	Red:   {"", "兵", "炮", "車", "馬", "相", "仕", "帥"},
+	Black: {"", "卒", "砲", "车", "马", "象", "士", "将"},
+}
+
+// ANSI 颜色
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[1;31m"
+	ansiBlack = "\x1b[1m"
+	ansiDim   = "\x1b[2m"
+)
+
+type TextOptions struct {
+	Style TextStyle
+	// Color 为 true 时用 ANSI 颜色区分双方
+	Color bool
+	// Flip 为 true 时黑方在下
+	Flip bool
+	// Coordinates 为 true 时在左边和下边显示 ICCS 坐标
+	Coordinates bool
+}
+
+// textMarks 是空交叉点、九宫中的空交叉点和河界的写法
+type textMarks struct {
+	empty  string
+	palace string
+	river  string
+}
+
+var asciiMarks = textMarks{".", "+", strings.Repeat("~", 17)}
+
+// 汉字和全角符号在终端中占两列
+var chineseMarks = textMarks{"．", "＋", "～～～～楚河～漢界～～～～"}
+
+func inPalace(file int, rank int) bool {
+	return file >= 3 && file <= 5 && (rank <= 2 || rank >= 7)
+}
+
+// Text 把局面画成文本，每行是棋盘的一行，红方默认在下
+func (b *Board) Text(opts TextOptions) string {
+	marks := asciiMarks
+	if opts.Style == TextChinese {
+		marks = chineseMarks
+	}
+	paint := func(s string, color string) string {
+		if !opts.Color {
+			return s
+		}
+		return color + s + ansiReset
+	}
+	var sb strings.Builder
+	indent := ""
+	if opts.Coordinates {
+		indent = "  "
+	}
+	for i := 0; i < 10; i++ {
+		rank := 9 - i
+		if opts.Flip {
+			rank = i
+		}
+		if rank == 4 && !opts.Flip || rank == 5 && opts.Flip {
+			sb.WriteString(indent + paint(marks.river, ansiDim) + "\n")
+		}
+		if opts.Coordinates {
+			sb.WriteString(string(rune('0'+rank)) + " ")
+		}
+		for j := 0; j < 9; j++ {
+			file := j
+			if opts.Flip {
+				file = 8 - j
+			}
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			piece := b.PieceAt(Square(file, rank))
+			switch {
+			case piece == nil && inPalace(file, rank):
+				sb.WriteString(paint(marks.palace, ansiDim))
+			case piece == nil:
+				sb.WriteString(paint(marks.empty, ansiDim))
+			default:
+				s := string(PieceSymbol(piece))
+				if opts.Style == TextChinese {
+					s = textGlyphs[piece.Color][piece.PieceType]
+				}
+				color := ansiBlack
+				if piece.Color == Red {
+					color = ansiRed
+				}
+				sb.WriteString(paint(s, color))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	if opts.Coordinates {
+		sb.WriteString(indent)
+		for j := 0; j < 9; j++ {
+			file := j
+			if opts.Flip {
+				file = 8 - j
+			}
+			if j > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteRune(rune('a' + file))
+			if opts.Style == TextChinese && j < 8 {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// String 返回带坐标的 ASCII 棋盘和 FEN
+func (b *Board) String() string {
+	return b.Text(TextOptions{Coordinates: true}) + b.Fen()
+}
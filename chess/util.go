@@ -69,7 +69,8 @@ func Or(nums ...*uint256.Int) *uint256.Int {
 	return z
 }
 
-func BbPrint(bb *uint256.Int) {
+// BbString 把位棋盘画成 1 和 . 组成的文本，红方在下
+func BbString(bb *uint256.Int) string {
 	builder := strings.Builder{}
 	for _, sq := range Squares180 {
 		mask := &BbSquares[sq]
@@ -87,7 +88,12 @@ func BbPrint(bb *uint256.Int) {
 			fmt.Fprint(&builder, " ")
 		}
 	}
-	print(builder.String())
+	return builder.String()
+}
+
+// BbPrint 把位棋盘输出到标准错误，用于调试
+func BbPrint(bb *uint256.Int) {
+	print(BbString(bb))
 }
 
 func Abs(n int) int {
@@ -0,0 +1,35 @@
+package chess
+
+var (
+	zobristPieces [2][8][256]uint64
+	zobristTurn   uint64
+)
+
+func colorIndex(color bool) int {
+	if color {
+		return 0
+	}
+	return 1
+}
+
+func init() {
+	// splitmix64，固定种子保证每次运行哈希值一致
+	seed := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for c := 0; c < 2; c++ {
+		for t := Pawn; t <= King; t++ {
+			for _, sq := range Squares {
+				if sq != 0 {
+					zobristPieces[c][t][sq] = next()
+				}
+			}
+		}
+	}
+	zobristTurn = next()
+}
@@ -0,0 +1,157 @@
+// gochess-book 用于生成和查询开局库。
+//
+//	gochess-book build -o book.bin [-maxply 30] [-min 2] games.pgn games.xqf...
+//	gochess-book probe -book book.bin [-fen FEN]
+//
+// 对局文件根据扩展名读取，.pgn 文件中可以有多局，.xqf 是象棋演播室的棋谱，其他文件是 DhtmlXQ 棋谱，
+// 只使用每局的主线。不是 DhtmlXQ 的文本文件按每行一局读取，依次是从初始局面开始的 ICCS 着法，
+// 最后是结果 (1-0, 0-1, 1/2-1/2, *)，以 # 开头的行会被忽略。
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clysto/gochess/book"
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/dhtmlxq"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/pgn"
+	"github.com/clysto/gochess/xqf"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: gochess-book build|probe [flags]")
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "build":
+		build(os.Args[2:])
+	case "probe":
+		probe(os.Args[2:])
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+}
+
+func build(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	output := fs.String("o", "book.bin", "output book file")
+	maxPly := fs.Int("maxply", 30, "number of plies to record from each game")
+	minGames := fs.Int("min", 1, "minimum number of games for a move to be kept")
+	fs.Parse(args)
+
+	builder := book.NewBuilder(*maxPly)
+	games := 0
+	for _, path := range fs.Args() {
+		n, err := addGames(builder, path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		games += n
+	}
+	bk := builder.Book(*minGames)
+	if err := bk.Save(*output); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d games, %d positions written to %s\n", games, bk.Positions(), *output)
+}
+
+// loadGames 根据扩展名读取对局文件中的所有棋谱
+func loadGames(path string) ([]*game.Game, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".xqf" {
+		g, err := xqf.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return []*game.Game{g}, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	switch {
+	case ext == ".pgn":
+		return pgn.ReadAll(strings.NewReader(text))
+	case strings.Contains(text, "[DhtmlXQ"):
+		g, err := dhtmlxq.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return []*game.Game{g}, nil
+	}
+	return readLines(text)
+}
+
+// readLines 读取每行一局的 ICCS 着法
+func readLines(text string) ([]*game.Game, error) {
+	var games []*game.Game
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		g := game.NewGame()
+		g.Result = chess.ParseResult(fields[len(fields)-1])
+		if g.Result != chess.NoResult || fields[len(fields)-1] == "*" {
+			fields = fields[:len(fields)-1]
+		}
+		node := g.Root
+		for _, text := range fields {
+			move, err := chess.ParseMove(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			node = node.Add(move)
+		}
+		games = append(games, g)
+	}
+	return games, scanner.Err()
+}
+
+// addGames 把文件中每一局的主线加入开局库
+func addGames(builder *book.Builder, path string) (int, error) {
+	games, err := loadGames(path)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, g := range games {
+		b, err := g.Board()
+		if err != nil {
+			return i, fmt.Errorf("%s: game %d: %v", path, i+1, err)
+		}
+		if err := builder.AddGame(b, g.MainLine(), g.Result); err != nil {
+			return i, fmt.Errorf("%s: game %d: %v", path, i+1, err)
+		}
+	}
+	return len(games), nil
+}
+
+func probe(args []string) {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	path := fs.String("book", "book.bin", "book file")
+	fen := fs.String("fen", chess.StartingFen, "position to probe")
+	fs.Parse(args)
+
+	bk, err := book.Open(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := chess.NewBoardFromFen(*fen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range bk.Moves(b) {
+		fmt.Printf("%s weight %d wins %d draws %d losses %d\n", e.Move, e.Weight, e.Wins, e.Draws, e.Losses)
+	}
+}
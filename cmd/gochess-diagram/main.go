@@ -0,0 +1,118 @@
+// gochess-diagram 把局面画成 PNG 或者 SVG 棋图，或者把一局棋画成 GIF 动画，根据输出文件的扩展名选择格式。
+//
+//	gochess-diagram [-fen FEN] [-width 760] [-flip] [-coords] [-arrows h2e2,b0c2] [-highlight e0,e9] -o board.png
+//	gochess-diagram -game game.pgn [-width 480] [-delay 1s] -o game.gif
+//
+// 棋谱可以是 PGN、XQF 或者 DhtmlXQ 格式，动画只包括主线。
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/dhtmlxq"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/pgn"
+	"github.com/clysto/gochess/render"
+	"github.com/clysto/gochess/xqf"
+)
+
+// loadGame 根据扩展名读取棋谱文件，PGN 文件中有多局时只读取第一局
+func loadGame(path string) (*game.Game, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".xqf" {
+		return xqf.Load(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if ext == ".pgn" {
+		return pgn.NewReader(f).Read()
+	}
+	return dhtmlxq.Read(f)
+}
+
+// parseList 解析逗号分隔的着法或者格子
+func parseList(s string, parse func(string) error) {
+	if s == "" {
+		return
+	}
+	for _, item := range strings.Split(s, ",") {
+		if err := parse(strings.TrimSpace(item)); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func main() {
+	fen := flag.String("fen", "", "position to draw, the initial position if empty")
+	output := flag.String("o", "board.png", "output file, .png or .svg")
+	width := flag.Int("width", 760, "image width in pixels, 0 for the size of the artwork")
+	flip := flag.Bool("flip", false, "draw the board from black's side")
+	coordinates := flag.Bool("coords", false, "draw ICCS coordinates")
+	arrows := flag.String("arrows", "", "arrows to draw, comma separated ICCS moves")
+	highlights := flag.String("highlight", "", "squares to highlight, comma separated")
+	gameFile := flag.String("game", "", "game record to animate when the output is .gif")
+	delay := flag.Duration("delay", render.DefaultDelay, "time each move is shown in the animation")
+	flag.Parse()
+
+	b := chess.NewBoard()
+	if *fen != "" {
+		var err error
+		if b, err = chess.NewBoardFromFen(*fen); err != nil {
+			log.Fatal(err)
+		}
+	}
+	opts := &render.Options{Width: *width, Flip: *flip, Coordinates: *coordinates}
+	parseList(*arrows, func(s string) error {
+		move, err := chess.ParseMove(s)
+		if err == nil {
+			opts.Arrows = append(opts.Arrows, render.Arrow{From: move.FromSquare, To: move.ToSquare})
+		}
+		return err
+	})
+	parseList(*highlights, func(s string) error {
+		sq, err := chess.ParseSquare(s)
+		if err == nil {
+			opts.Highlights = append(opts.Highlights, sq)
+		}
+		return err
+	})
+
+	var g *game.Game
+	ext := strings.ToLower(filepath.Ext(*output))
+	if ext == ".gif" {
+		if *gameFile == "" {
+			log.Fatal("-game is required for a .gif output")
+		}
+		var err error
+		if g, err = loadGame(*gameFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch ext {
+	case ".gif":
+		err = render.GIF(f, g, &render.GIFOptions{Options: *opts, Delay: *delay})
+	case ".svg":
+		err = render.SVG(f, b, opts)
+	default:
+		err = render.PNG(f, b, opts)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
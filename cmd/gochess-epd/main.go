@@ -0,0 +1,90 @@
+// gochess-epd 用 EPD 测试集检验引擎的战术能力。
+//
+//	gochess-epd [-depth 0] [-movetime 0] [-nodes 0] [-options Hash=64,Threads=1] [-json] suite.epd...
+//
+// 每个局面单独搜索，输出引擎的着法是否符合 bm 并且避开了 am。没有给出任何搜索限制时每个局面搜索 1 秒。
+// -json 时输出包括每个局面结果的 JSON，便于长期跟踪。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/epd"
+)
+
+func main() {
+	depth := flag.Int("depth", 0, "search depth per position, 0 for no limit")
+	moveTime := flag.Duration("movetime", 0, "search time per position, 0 for no limit")
+	nodes := flag.Uint64("nodes", 0, "nodes per position, 0 for no limit")
+	options := flag.String("options", "", "engine options, Name=Value,...")
+	asJSON := flag.Bool("json", false, "print the summary and per-position results as JSON")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatal("no EPD files")
+	}
+	// 只使用命令行给出的限制，只给 -depth 或 -nodes 时结果可以复现，都没有给出时每个局面搜索 1 秒
+	if *depth <= 0 && *moveTime <= 0 && *nodes == 0 {
+		*moveTime = time.Second
+	}
+	var positions []*epd.Position
+	for _, path := range flag.Args() {
+		p, err := epd.Load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		positions = append(positions, p...)
+	}
+	opts, err := engine.ParseOptions(*options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	e := engine.NewEngine()
+	if err := e.SetOptions(opts); err != nil {
+		log.Fatal(err)
+	}
+
+	limits := engine.Limits{Depth: *depth, MoveTime: *moveTime, Nodes: *nodes}
+	summary := epd.Run(e, positions, limits, func(r *epd.Result) {
+		if *asJSON {
+			return
+		}
+		status := "ok  "
+		if !r.Solved {
+			status = "FAIL"
+		}
+		id := r.ID
+		if id == "" {
+			id = r.Fen
+		}
+		if r.Error != "" {
+			fmt.Printf("%s %s: %s\n", status, id, r.Error)
+			return
+		}
+		line := fmt.Sprintf("%s %s: move %s", status, id, r.Move)
+		if len(r.Best) > 0 {
+			line += " bm " + strings.Join(r.Best, ",")
+		}
+		if len(r.Avoid) > 0 {
+			line += " am " + strings.Join(r.Avoid, ",")
+		}
+		fmt.Printf("%s score %d depth %d nodes %d time %d ms\n", line, r.Score, r.Depth, r.Nodes, r.Time.Milliseconds())
+	})
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(summary); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("solved %d/%d\nnodes %d\ntime %d ms\n",
+		summary.Solved, summary.Positions, summary.Nodes, summary.Time.Milliseconds())
+}
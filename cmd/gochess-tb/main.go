@@ -0,0 +1,93 @@
+// gochess-tb 用于生成和查询残局库。
+//
+//	gochess-tb generate -dir tablebases KRvK KRvKA...
+//	gochess-tb probe -dir tablebases -fen FEN
+//
+// 生成时吃子后进入的子力组合也会一起生成并保存。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/tablebase"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: gochess-tb generate|probe [flags]")
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "generate":
+		generate(os.Args[2:])
+	case "probe":
+		probe(os.Args[2:])
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+}
+
+func generate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	dir := fs.String("dir", "tablebases", "output directory")
+	fs.Parse(args)
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	tb, err := tablebase.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	existing := map[string]bool{}
+	for _, sig := range tb.Signatures() {
+		existing[sig] = true
+	}
+	for _, sig := range fs.Args() {
+		start := time.Now()
+		if _, err := tb.Generate(sig); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s generated in %v\n", sig, time.Since(start).Round(time.Millisecond))
+	}
+	sigs := tb.Signatures()
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		if existing[sig] {
+			continue
+		}
+		path := filepath.Join(*dir, sig+tablebase.Extension)
+		if err := tb.Table(sig).Save(path); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s written to %s\n", sig, path)
+	}
+}
+
+func probe(args []string) {
+	fs := flag.NewFlagSet("probe", flag.ExitOnError)
+	dir := fs.String("dir", "tablebases", "tablebase directory")
+	fen := fs.String("fen", "", "position to probe")
+	fs.Parse(args)
+
+	tb, err := tablebase.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := chess.NewBoardFromFen(*fen)
+	if err != nil {
+		log.Fatal(err)
+	}
+	move, wdl, dtm, ok := tb.BestMove(b)
+	if !ok {
+		log.Fatalf("position not found in %s", *dir)
+	}
+	fmt.Printf("%v dtm %d bestmove %v\n", wdl, dtm, move)
+}
@@ -0,0 +1,59 @@
+// gochess-tune 用 Texel 方法调整评估参数。
+//
+//	gochess-tune [-params in.txt] [-o params.txt] [-iterations 100] [-k 0] [-qsearch] positions.txt...
+//
+// 局面文件每行是 FEN 和对局结果 (1-0, 0-1, 1/2-1/2)，以 # 开头的行会被忽略。
+// 生成的参数文件可以通过引擎的 EvalParams 选项加载。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/tune"
+)
+
+func main() {
+	input := flag.String("params", "", "initial parameter file, built-in parameters if empty")
+	output := flag.String("o", "params.txt", "output parameter file")
+	iterations := flag.Int("iterations", 100, "maximum number of iterations")
+	k := flag.Float64("k", 0, "scaling constant, fitted to the positions if 0")
+	quiescence := flag.Bool("qsearch", false, "evaluate the end of the quiescence search instead of the position itself")
+	flag.Parse()
+
+	params := engine.DefaultParams()
+	if *input != "" {
+		var err error
+		if params, err = engine.LoadParams(*input); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var positions []tune.Position
+	for _, path := range flag.Args() {
+		p, err := tune.LoadPositions(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		positions = append(positions, p...)
+	}
+	if len(positions) == 0 {
+		log.Fatal("no positions")
+	}
+
+	tuner := tune.NewTuner(params, positions, *quiescence)
+	if *k > 0 {
+		tuner.K = *k
+	} else {
+		tuner.FitK()
+	}
+	fmt.Printf("%d positions, K = %.4f, loss %.6f\n", len(positions), tuner.K, tuner.Loss())
+	tuner.Run(*iterations, func(iteration int, changed int, loss float64) {
+		fmt.Printf("iteration %d: %d parameters changed, loss %.6f\n", iteration, changed, loss)
+	})
+	if err := tuner.Params.Save(*output); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("parameters written to %s\n", *output)
+}
@@ -0,0 +1,50 @@
+// gochess-ucci 是 UCCI/UCI 协议的引擎程序。
+//
+//	gochess-ucci bench [-depth 6] [-json]
+//
+// 搜索固定的局面并输出总节点数，节点数变化说明搜索的行为有变化。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/protocol"
+)
+
+func bench(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	depth := flags.Int("depth", engine.BenchDepth, "search depth")
+	asJSON := flags.Bool("json", false, "print the result and per-position statistics as JSON")
+	flags.Parse(args)
+
+	result := engine.Bench(*depth)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	for i, position := range result.Positions {
+		fmt.Printf("position %d: %s bestmove %s score %d nodes %d\n",
+			i+1, position.Fen, position.BestMove, position.Score, position.Stats.Nodes)
+	}
+	fmt.Printf("depth %d\nnodes %d\ntime %d ms\nnps %d\n", result.Depth, result.Nodes, result.Time.Milliseconds(), result.NPS)
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		bench(os.Args[2:])
+		return
+	}
+	session := protocol.NewSession(engine.NewEngine(), os.Stdout)
+	if err := session.Run(os.Stdin); err != nil {
+		log.Fatal(err)
+	}
+}
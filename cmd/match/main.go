@@ -0,0 +1,155 @@
+// match 让两个引擎互相对局并统计成绩，可以用 SPRT 检验 Elo 是否提高。
+//
+//	match [-engine1 builtin] [-engine2 path] [-options1 Hash=64,Threads=1] [-openings fens.txt]
+//	      [-games 1000] [-concurrency 8] [-tc 10+0.1] [-pgn games.pgn] [-sprt 0,5]
+//
+// 引擎为 builtin 时使用内置引擎，否则启动外部的 UCCI 或 UCI 引擎。成绩以第一个引擎为视角。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/match"
+	"github.com/clysto/gochess/pgn"
+)
+
+// parseSPRT 解析 "elo0,elo1" 格式的 SPRT 假设
+func parseSPRT(s string, alpha float64, beta float64) (*match.SPRT, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid sprt %q", s)
+	}
+	elo0, err0 := strconv.ParseFloat(parts[0], 64)
+	elo1, err1 := strconv.ParseFloat(parts[1], 64)
+	if err0 != nil || err1 != nil || elo0 >= elo1 {
+		return nil, fmt.Errorf("invalid sprt %q", s)
+	}
+	return &match.SPRT{Elo0: elo0, Elo1: elo1, Alpha: alpha, Beta: beta}, nil
+}
+
+// player 返回第 index 个引擎的配置，内置引擎没有指定名称时用序号区分
+func player(index int, path string, name string, options string) match.PlayerConfig {
+	opts, err := engine.ParseOptions(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config := match.PlayerConfig{Name: name, Options: opts}
+	if path == "builtin" {
+		if name == "" {
+			config.Name = fmt.Sprintf("gochess-%d", index)
+		}
+	} else {
+		fields := strings.Fields(path)
+		config.Path, config.Args = fields[0], fields[1:]
+	}
+	return config
+}
+
+func main() {
+	engine1 := flag.String("engine1", "builtin", "first engine, builtin or the command of an external engine")
+	engine2 := flag.String("engine2", "builtin", "second engine, builtin or the command of an external engine")
+	name1 := flag.String("name1", "", "name of the first engine")
+	name2 := flag.String("name2", "", "name of the second engine")
+	options1 := flag.String("options1", "", "options of the first engine, Name=Value,...")
+	options2 := flag.String("options2", "", "options of the second engine, Name=Value,...")
+	openings := flag.String("openings", "", "file with one opening FEN per line, the starting position if empty")
+	games := flag.Int("games", 100, "maximum number of games")
+	concurrency := flag.Int("concurrency", 0, "number of games played at the same time, the number of CPUs if 0")
+	tc := flag.String("tc", "10+0.1", "time control in seconds, base+increment")
+	moveTime := flag.Duration("movetime", 0, "fixed time per move instead of a clock")
+	nodes := flag.Uint64("nodes", 0, "node limit per move")
+	depth := flag.Int("depth", 0, "depth limit per move")
+	pgnPath := flag.String("pgn", "", "append games to this PGN file")
+	sprt := flag.String("sprt", "", "stop early when the SPRT for elo0,elo1 is decided")
+	alpha := flag.Float64("alpha", 0.05, "SPRT type I error")
+	beta := flag.Float64("beta", 0.05, "SPRT type II error")
+	resignScore := flag.Int("resign-score", 1000, "resign adjudication score")
+	resignMoves := flag.Int("resign-moves", 3, "resign adjudication moves, 0 to disable")
+	drawScore := flag.Int("draw-score", 10, "draw adjudication score")
+	drawMoves := flag.Int("draw-moves", 10, "draw adjudication moves, 0 to disable")
+	drawPly := flag.Int("draw-ply", 80, "first ply where draw adjudication applies")
+	maxPly := flag.Int("maxply", 400, "draw games longer than this, 0 to disable")
+	flag.Parse()
+
+	config := match.Config{
+		Players:     [2]match.PlayerConfig{player(1, *engine1, *name1, *options1), player(2, *engine2, *name2, *options2)},
+		Games:       *games,
+		Concurrency: *concurrency,
+		Adjudication: match.Adjudication{
+			ResignScore: *resignScore,
+			ResignMoves: *resignMoves,
+			DrawScore:   *drawScore,
+			DrawMoves:   *drawMoves,
+			DrawPly:     *drawPly,
+			MaxPly:      *maxPly,
+		},
+		Event: "gochess match",
+	}
+	if *moveTime == 0 && *nodes == 0 && *depth == 0 {
+		var err error
+		if config.TimeControl, err = match.ParseTimeControl(*tc); err != nil {
+			log.Fatal(err)
+		}
+	}
+	config.TimeControl.MoveTime = *moveTime
+	config.TimeControl.Nodes = *nodes
+	config.TimeControl.Depth = *depth
+	if *openings != "" {
+		var err error
+		if config.Openings, err = match.LoadOpenings(*openings); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *sprt != "" {
+		var err error
+		if config.SPRT, err = parseSPRT(*sprt, *alpha, *beta); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var out *os.File
+	if *pgnPath != "" {
+		var err error
+		out, err = os.OpenFile(*pgnPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+
+	m := match.New(config)
+	var once sync.Once
+	m.OnGame = func(round int, result *match.GameResult, stats match.Stats) {
+		if out != nil {
+			if err := pgn.Write(out, result.Game); err != nil {
+				once.Do(func() { log.Print(err) })
+			}
+		}
+		elo, margin := stats.Elo()
+		line := fmt.Sprintf("game %d: %s - %s %s {%s}  score %d-%d-%d  elo %.1f +/- %.1f",
+			round, result.Game.Tag("Red"), result.Game.Tag("Black"), result.Result, result.Reason,
+			stats.Wins, stats.Losses, stats.Draws, elo, margin)
+		if config.SPRT != nil {
+			lower, upper := config.SPRT.Bounds()
+			line += fmt.Sprintf("  llr %.2f (%.2f, %.2f)", config.SPRT.LLR(stats), lower, upper)
+		}
+		fmt.Println(line)
+	}
+	stats, err := m.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	elo, margin := stats.Elo()
+	fmt.Printf("finished: %d games, score %d-%d-%d (%.1f%%), elo %.1f +/- %.1f\n",
+		stats.Games(), stats.Wins, stats.Losses, stats.Draws, stats.Score()*100, elo, margin)
+	if config.SPRT != nil {
+		fmt.Printf("sprt: %s\n", m.Decision())
+	}
+}
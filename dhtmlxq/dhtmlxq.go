@@ -0,0 +1,349 @@
+// Package dhtmlxq 读写论坛中嵌入棋谱使用的 DhtmlXQ 格式，例如
+//
+//	[DhtmlXQ]
+//	[DhtmlXQ_red]红方[/DhtmlXQ_red]
+//	[DhtmlXQ_binit]8979695949392919097717866646260600102030405060708012720323436383[/DhtmlXQ_binit]
+//	[DhtmlXQ_movelist]77477062[/DhtmlXQ_movelist]
+//	[DhtmlXQ_move_0_2_1]1022[/DhtmlXQ_move_0_2_1]
+//	[DhtmlXQ_comment1_2]变着的注释[/DhtmlXQ_comment1_2]
+//	[/DhtmlXQ]
+//
+// 每个格子用两个数字表示，第一个是从左往右的纵线，第二个是从上 (黑方底线) 往下的横线。
+// 主线的编号是 0，DhtmlXQ_move_A_B_C 表示从第 A 条变例的第 B 步开始的第 C 条变例，
+// DhtmlXQ_commentC_B 是第 C 条变例第 B 步之后的注释，主线的注释省略变例编号。
+package dhtmlxq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+var ErrInvalidGame = errors.New("dhtmlxq: invalid game")
+
+// pieces 是 binit 中 32 个棋子的顺序，前 16 个是红方
+var pieces = [16]uint8{
+	chess.Rook, chess.Knight, chess.Bishop, chess.Advisor, chess.King, chess.Advisor, chess.Bishop, chess.Knight, chess.Rook,
+	chess.Cannon, chess.Cannon, chess.Pawn, chess.Pawn, chess.Pawn, chess.Pawn, chess.Pawn,
+}
+
+// 标签名和 PGN 标签的对应关系
+var tagNames = []struct {
+	dhtmlxq string
+	pgn     string
+}{
+	{"title", "Title"},
+	{"event", "Event"},
+	{"date", "Date"},
+	{"place", "Site"},
+	{"round", "Round"},
+	{"red", "Red"},
+	{"black", "Black"},
+	{"red_team", "RedTeam"},
+	{"black_team", "BlackTeam"},
+	{"open", "Opening"},
+	{"author", "Annotator"},
+}
+
+var results = map[chess.Result]string{
+	chess.RedWins:   "红胜",
+	chess.BlackWins: "黑胜",
+	chess.Draw:      "和棋",
+	chess.NoResult:  "未知",
+}
+
+func squareText(sq uint8) string {
+	return fmt.Sprintf("%d%d", chess.SquareFile(sq)-3, 12-chess.SquareRank(sq))
+}
+
+func parseSquare(s string) (uint8, bool) {
+	if len(s) != 2 || s[0] < '0' || s[0] > '8' || s[1] < '0' || s[1] > '9' {
+		return 0, false
+	}
+	return chess.Square(int(s[0]-'0'), int('9'-s[1])), true
+}
+
+func moveText(move *chess.Move) string {
+	return squareText(move.FromSquare) + squareText(move.ToSquare)
+}
+
+// 注释中的换行写成 ||
+func encodeComment(s string) string {
+	return strings.Replace(s, "\n", "||", -1)
+}
+
+func decodeComment(s string) string {
+	return strings.Replace(s, "||", "\n", -1)
+}
+
+var fieldPattern = regexp.MustCompile(`(?s)\[DhtmlXQ_(\w+)\](.*?)\[/DhtmlXQ_(\w+)\]`)
+
+// Parse 解析文本中的第一个 DhtmlXQ 棋谱，文本中可以有其他的内容
+func Parse(text string) (*game.Game, error) {
+	if start := strings.Index(text, "[DhtmlXQ]"); start >= 0 {
+		text = text[start:]
+		if end := strings.Index(text, "[/DhtmlXQ]"); end >= 0 {
+			text = text[:end]
+		}
+	}
+	fields := map[string]string{}
+	for _, m := range fieldPattern.FindAllStringSubmatch(text, -1) {
+		if m[1] == m[3] {
+			fields[m[1]] = strings.TrimSpace(m[2])
+		}
+	}
+	if len(fields) == 0 {
+		return nil, ErrInvalidGame
+	}
+
+	g := game.NewGame()
+	for _, name := range tagNames {
+		if value := fields[name.dhtmlxq]; value != "" {
+			g.SetTag(name.pgn, value)
+		}
+	}
+	for result, text := range results {
+		if fields["result"] == text {
+			g.Result = result
+		}
+	}
+	b := chess.NewBoard()
+	if binit := fields["binit"]; binit != "" {
+		var err error
+		if b, err = parseBinit(binit, fields["movelist"]); err != nil {
+			return nil, err
+		}
+		if fen := b.Fen(); fen != chess.StartingFen {
+			g.SetTag("FEN", fen)
+		}
+	}
+
+	// lines[id] 是第 id 条变例从根节点开始的节点，下标是步数
+	lines := map[int][]*game.Node{}
+	var err error
+	if lines[0], err = addMoves(b.Copy(), []*game.Node{g.Root}, fields["movelist"]); err != nil {
+		return nil, err
+	}
+	type branch struct {
+		parent, step, id int
+		moves            string
+	}
+	var branches []branch
+	for name, value := range fields {
+		var br branch
+		if n, _ := fmt.Sscanf(name, "move_%d_%d_%d", &br.parent, &br.step, &br.id); n == 3 && br.id > 0 {
+			br.moves = value
+			branches = append(branches, br)
+		}
+	}
+	// 变例的编号总是大于它所在的变例
+	sort.Slice(branches, func(i, j int) bool { return branches[i].id < branches[j].id })
+	for _, br := range branches {
+		parent, ok := lines[br.parent]
+		if !ok || br.step < 1 || br.step > len(parent) {
+			return nil, fmt.Errorf("dhtmlxq: invalid branch move_%d_%d_%d", br.parent, br.step, br.id)
+		}
+		prefix := append([]*game.Node(nil), parent[:br.step]...)
+		board := b.Copy()
+		for _, node := range prefix[1:] {
+			board.Push(node.Move)
+		}
+		if lines[br.id], err = addMoves(board, prefix, br.moves); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, value := range fields {
+		id, step := 0, 0
+		if n, _ := fmt.Sscanf(name, "comment%d_%d", &id, &step); n != 2 {
+			if n, _ := fmt.Sscanf(name, "comment%d", &step); n != 1 {
+				continue
+			}
+			id = 0
+		}
+		if line, ok := lines[id]; ok && step >= 0 && step < len(line) {
+			line[step].Comment = decodeComment(value)
+		}
+	}
+	return g, nil
+}
+
+// parseBinit 解析初始局面，DhtmlXQ 没有记录先走的一方，根据第一步着法判断
+func parseBinit(binit string, movelist string) (*chess.Board, error) {
+	if len(binit) != 64 {
+		return nil, ErrInvalidGame
+	}
+	b := chess.NewEmptyBoard()
+	for i := 0; i < 32; i++ {
+		sq, ok := parseSquare(binit[2*i : 2*i+2])
+		if !ok {
+			continue
+		}
+		if b.PieceAt(sq) != nil {
+			return nil, ErrInvalidGame
+		}
+		b.SetPieceAt(sq, &chess.Piece{PieceType: pieces[i%16], Color: i < 16})
+	}
+	if len(movelist) >= 2 {
+		if sq, ok := parseSquare(movelist[:2]); ok && b.PieceAt(sq) != nil {
+			b.SetTurn(b.PieceAt(sq).Color)
+		}
+	}
+	return chess.NewBoardFromFen(b.Fen())
+}
+
+// addMoves 在 line 的最后一个节点之后添加着法，b 是这个节点的局面，返回延长后的变例
+func addMoves(b *chess.Board, line []*game.Node, moves string) ([]*game.Node, error) {
+	if len(moves)%4 != 0 {
+		return nil, ErrInvalidGame
+	}
+	for i := 0; i < len(moves); i += 4 {
+		from, ok1 := parseSquare(moves[i : i+2])
+		to, ok2 := parseSquare(moves[i+2 : i+4])
+		move := &chess.Move{FromSquare: from, ToSquare: to}
+		if !ok1 || !ok2 || !b.IsLegal(move) {
+			return nil, fmt.Errorf("dhtmlxq: illegal move %s at %s", moves[i:i+4], b.Fen())
+		}
+		b.Push(move)
+		line = append(line, line[len(line)-1].Add(move))
+	}
+	return line, nil
+}
+
+func Read(r io.Reader) (*game.Game, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
+
+// binit 返回初始局面的编码，不在棋盘上的棋子写成 99
+func binit(b *chess.Board) (string, error) {
+	// 同类的棋子按照从各自的右边到左边的顺序排列
+	squares := chess.ScanReversed(b.Occupied())
+	order := func(sq uint8) int {
+		if b.PieceAt(sq).Color == chess.Red {
+			return -chess.SquareFile(sq)
+		}
+		return chess.SquareFile(sq)
+	}
+	sort.Slice(squares, func(i, j int) bool {
+		return order(squares[i]) < order(squares[j])
+	})
+	var slots [32]string
+	for _, sq := range squares {
+		piece := b.PieceAt(sq)
+		placed := false
+		for i, pieceType := range pieces {
+			if !piece.Color {
+				i += 16
+			}
+			if pieceType == piece.PieceType && slots[i] == "" {
+				slots[i] = squareText(sq)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return "", ErrInvalidGame
+		}
+	}
+	var sb strings.Builder
+	for _, s := range slots {
+		if s == "" {
+			s = "99"
+		}
+		sb.WriteString(s)
+	}
+	return sb.String(), nil
+}
+
+// Format 把棋谱编码为 DhtmlXQ 格式
+func Format(g *game.Game) (string, error) {
+	b, err := g.Board()
+	if err != nil {
+		return "", err
+	}
+	init, err := binit(b)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	field := func(name string, value string) {
+		fmt.Fprintf(&sb, "[DhtmlXQ_%s]%s[/DhtmlXQ_%s]\n", name, value, name)
+	}
+	sb.WriteString("[DhtmlXQ]\n")
+	field("ver", "www_dhtmlxq_com")
+	for _, name := range tagNames {
+		if value := g.Tag(name.pgn); value != "" {
+			field(name.dhtmlxq, value)
+		}
+	}
+	field("result", results[g.Result])
+	field("binit", init)
+
+	// 先序遍历着法树，每个不是主线的子节点开始一条新的变例，blocks 是注释和变例的字段
+	var blocks []string
+	nextID := 1
+	var walk func(node *game.Node, id int, step int) string
+	walk = func(node *game.Node, id int, step int) string {
+		var moves strings.Builder
+		var branches []func()
+		for {
+			if node.Comment != "" {
+				name := fmt.Sprint("comment", step)
+				if id > 0 {
+					name = fmt.Sprintf("comment%d_%d", id, step)
+				}
+				blocks = append(blocks, fmt.Sprintf("[DhtmlXQ_%s]%s[/DhtmlXQ_%s]\n", name, encodeComment(node.Comment), name))
+			}
+			if len(node.Children) == 0 {
+				break
+			}
+			for _, variation := range node.Children[1:] {
+				variation, parent, at := variation, id, step+1
+				branches = append(branches, func() {
+					branch := nextID
+					nextID++
+					name := fmt.Sprintf("move_%d_%d_%d", parent, at, branch)
+					text := moveText(variation.Move) + walk(variation, branch, at)
+					blocks = append(blocks, fmt.Sprintf("[DhtmlXQ_%s]%s[/DhtmlXQ_%s]\n", name, text, name))
+				})
+			}
+			node = node.Children[0]
+			step++
+			moves.WriteString(moveText(node.Move))
+		}
+		for _, branch := range branches {
+			branch()
+		}
+		return moves.String()
+	}
+	root := g.Root
+	if root == nil {
+		root = &game.Node{}
+	}
+	field("movelist", walk(root, 0, 0))
+	for _, c := range blocks {
+		sb.WriteString(c)
+	}
+	sb.WriteString("[/DhtmlXQ]\n")
+	return sb.String(), nil
+}
+
+func Write(w io.Writer, g *game.Game) error {
+	text, err := Format(g)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, text)
+	return err
+}
@@ -0,0 +1,123 @@
+package dhtmlxq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/pgn"
+)
+
+const startBinit = "8979695949392919097717866646260600102030405060708012720323436383"
+
+func pgnText(t *testing.T, g *game.Game) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := pgn.Write(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func readPGN(t *testing.T, text string) *game.Game {
+	t.Helper()
+	g, err := pgn.NewReader(strings.NewReader(text)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestParse(t *testing.T) {
+	text := `论坛里的一局棋：
+[DhtmlXQ]
+[DhtmlXQ_ver]www_dhtmlxq_com[/DhtmlXQ_ver]
+[DhtmlXQ_red]红方[/DhtmlXQ_red]
+[DhtmlXQ_black]黑方[/DhtmlXQ_black]
+[DhtmlXQ_result]红胜[/DhtmlXQ_result]
+[DhtmlXQ_binit]` + startBinit + `[/DhtmlXQ_binit]
+[DhtmlXQ_movelist]774770627967[/DhtmlXQ_movelist]
+[DhtmlXQ_comment0]中炮[/DhtmlXQ_comment0]
+[DhtmlXQ_comment2]屏风马||第二行[/DhtmlXQ_comment2]
+[DhtmlXQ_move_0_2_1]1242[/DhtmlXQ_move_0_2_1]
+[DhtmlXQ_comment1_2]顺炮[/DhtmlXQ_comment1_2]
+[/DhtmlXQ]
+以上`
+	g, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := readPGN(t, `[Red "红方"]
+[Black "黑方"]
+
+{中炮} 1. h2e2 h9g7 {屏风马
+第二行} (1... b7e7 {顺炮}) 2. h0g2 1-0`)
+	want.Result = chess.RedWins
+	if got := pgnText(t, g); got != pgnText(t, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, pgnText(t, want))
+	}
+}
+
+func TestFormat(t *testing.T) {
+	g := readPGN(t, `[Event "测试"]
+[Red "红方"]
+
+{开局} 1. h2e2 h9g7 (1... b7e7 {顺炮} 2. h0g2 (2. b0c2 {变着中的变着}) b9c7) 2. h0g2 {正常} i9h9 (2... c6c5) 3. i0h0 *`)
+	text, err := Format(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[DhtmlXQ_binit]" + startBinit + "[/DhtmlXQ_binit]",
+		"[DhtmlXQ_movelist]77477062796780708979[/DhtmlXQ_movelist]",
+		"[DhtmlXQ_comment0]开局[/DhtmlXQ_comment0]",
+		"[DhtmlXQ_move_0_2_1]124279671022[/DhtmlXQ_move_0_2_1]",
+		"[DhtmlXQ_move_1_3_2]1927[/DhtmlXQ_move_1_3_2]",
+		"[DhtmlXQ_comment2_3]变着中的变着[/DhtmlXQ_comment2_3]",
+		"[DhtmlXQ_move_0_4_3]2324[/DhtmlXQ_move_0_4_3]",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %s in\n%s", want, text)
+		}
+	}
+	again, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pgnText(t, again); got != pgnText(t, g) {
+		t.Errorf("got\n%s\nwant\n%s", got, pgnText(t, g))
+	}
+}
+
+func TestEndgame(t *testing.T) {
+	g := readPGN(t, `[FEN "4k4/9/9/9/9/9/9/9/3r5/5K3 b - - 0 1"]
+
+1... d1d0 0-1`)
+	g.Result = chess.BlackWins
+	buf := &bytes.Buffer{}
+	if err := Write(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Tag("FEN") != g.Tag("FEN") || pgnText(t, again) != pgnText(t, g) {
+		t.Errorf("got\n%s\nwant\n%s", pgnText(t, again), pgnText(t, g))
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, text := range []string{
+		"no game here",
+		"[DhtmlXQ_movelist]7740[/DhtmlXQ_movelist]",
+		"[DhtmlXQ_movelist]774[/DhtmlXQ_movelist]",
+		"[DhtmlXQ_movelist]7747[/DhtmlXQ_movelist][DhtmlXQ_move_0_5_1]1242[/DhtmlXQ_move_0_5_1]",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
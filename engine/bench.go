@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"time"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// BenchDepth 是 bench 默认的搜索深度
+const BenchDepth = 6
+
+// BenchFens 是固定的测试局面集合，用于比较搜索的速度和行为，修改这些局面会改变 bench 的节点数
+var BenchFens = []string{
+	chess.StartingFen,
+	"1rbakabr1/9/c1n3nc1/p1p1p1p1p/9/9/P1P1P1P1P/1CN1C1N2/9/1RBAKABR1 w - - 10 6",
+	"r1bakabr1/9/1cn3n1c/p1p1p3p/6p2/2P3P2/P3P3P/1CN3NC1/9/1RBAKAB1R w - - 10 6",
+	"1rbakabr1/9/1cn3n2/p3p1p1p/2p6/2P6/P3P1PcP/2N1C1NC1/9/1RBAKABR1 w - - 12 7",
+	"r1bakabr1/9/2n1c1n2/p1p1p1pRp/9/9/PcP1P1P1P/1CN1C1N2/9/R1BAKAB2 w - - 10 6",
+	"1rbakabnr/9/n1c4c1/p3p1p1p/2p6/6P2/P1P1P3P/1C2B1N1C/9/RNBAKA2R w - - 8 5",
+	"r1bakabr1/9/1c4n2/pC2p1R1p/2p6/4P4/P1P1N1P1P/4C1Nc1/9/R1BAKAB2 w - - 1 10",
+	"3k5/9/4b4/9/2p6/9/9/4B4/4N4/4K4 w - - 0 1",
+	"4k4/4a4/3a5/9/9/9/9/9/9/3RK4 w - - 0 1",
+}
+
+// BenchPosition 是 bench 中一个局面的搜索结果
+type BenchPosition struct {
+	Fen      string `json:"fen"`
+	BestMove string `json:"bestmove"`
+	Score    int    `json:"score"`
+	Stats    Stats  `json:"stats"`
+}
+
+// BenchResult 是 bench 的结果，Nodes 对于同一个版本的引擎总是相同的，可以作为搜索行为的签名
+type BenchResult struct {
+	Depth     int             `json:"depth"`
+	Positions []BenchPosition `json:"positions"`
+	Nodes     uint64          `json:"nodes"`
+	Time      time.Duration   `json:"time_ns"`
+	NPS       uint64          `json:"nps"`
+}
+
+// Bench 用默认选项和单线程把每个局面搜索到 depth 层，每个局面之前清空置换表，结果只和引擎的代码有关
+func Bench(depth int) BenchResult {
+	if depth <= 0 {
+		depth = BenchDepth
+	}
+	e := NewEngine()
+	result := BenchResult{Depth: depth}
+	for _, fen := range BenchFens {
+		b, err := chess.NewBoardFromFen(fen)
+		if err != nil {
+			panic(err)
+		}
+		e.NewGame()
+		start := time.Now()
+		r := e.Search(b, Limits{Depth: depth})
+		result.Time += time.Since(start)
+		position := BenchPosition{Fen: fen, Score: r.Score, Stats: r.Stats}
+		if r.BestMove != nil {
+			position.BestMove = r.BestMove.String()
+		}
+		result.Positions = append(result.Positions, position)
+		result.Nodes += r.Nodes
+	}
+	if ms := uint64(result.Time.Milliseconds()); ms > 0 {
+		result.NPS = result.Nodes * 1000 / ms
+	}
+	return result
+}
@@ -0,0 +1,401 @@
+package engine
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clysto/gochess/book"
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/tablebase"
+)
+
+func TestSearchMateInOne(t *testing.T) {
+	b, err := chess.NewBoardFromFen("4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	var last *Info
+	e.OnInfo = func(info *Info) {
+		last = info
+	}
+	result := e.Search(b, Limits{Depth: 3})
+	if result.BestMove == nil || result.BestMove.String() != "a0a9" {
+		t.Fatalf("best move = %v, want a0a9", result.BestMove)
+	}
+	if last == nil || last.Mate() != 1 {
+		t.Errorf("expected mate in 1, got %+v", last)
+	}
+}
+
+func TestSearchStartingPosition(t *testing.T) {
+	b := chess.NewBoard()
+	fen := b.Fen()
+	e := NewEngine()
+	result := e.Search(b, Limits{Depth: 3})
+	if result.BestMove == nil || !b.IsLegal(result.BestMove) {
+		t.Fatalf("illegal best move %v", result.BestMove)
+	}
+	if b.Fen() != fen {
+		t.Errorf("search modified the board: %s", b.Fen())
+	}
+}
+
+func TestSearchNoLegalMoves(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("R3k4/1R7/9/9/9/9/9/9/9/3K5 b - - 0 1")
+	result := NewEngine().Search(b, Limits{Depth: 2})
+	if result.BestMove != nil {
+		t.Errorf("expected no move, got %v", result.BestMove)
+	}
+}
+
+func TestSearchDeterministic(t *testing.T) {
+	b := chess.NewBoard()
+	first := NewEngine().Search(b, Limits{Depth: 4})
+	second := NewEngine().Search(b, Limits{Depth: 4})
+	if *first.BestMove != *second.BestMove || first.Nodes != second.Nodes || first.Score != second.Score {
+		t.Errorf("single-threaded search is not deterministic: %+v vs %+v", first, second)
+	}
+}
+
+func TestSearchThreads(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1")
+	e := NewEngine()
+	if err := e.SetOption("Threads", "4"); err != nil {
+		t.Fatal(err)
+	}
+	result := e.Search(b, Limits{Depth: 4})
+	if result.BestMove == nil || result.BestMove.String() != "a0a9" {
+		t.Errorf("best move = %v, want a0a9", result.BestMove)
+	}
+	result = e.Search(chess.NewBoard(), Limits{Nodes: 20000})
+	if result.BestMove == nil || result.Nodes < 20000 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+// 用于比较多线程的扩展性：go test -bench SearchThreads -benchtime 1x ./engine
+// nps 是每秒节点数，ms-to-depth 是所有局面搜索到深度 5 的总用时，speedup 是相对单线程的加速比。
+//
+// 单核 Xeon 上的结果，线程只能轮流运行，只反映多线程的额外开销：
+//
+//	threads  nps     ms-to-depth  speedup
+//	1        107165  907          1.00
+//	2        93639   1256         0.72
+//	4        133506  1205         0.75
+//	8        108879  1982         0.46
+//
+// 还没有多核机器上的数据，多线程的加速效果需要在多核机器上运行后补充。
+func BenchmarkSearchThreads(b *testing.B) {
+	var single time.Duration
+	for _, threads := range []string{"1", "2", "4", "8"} {
+		b.Run(threads, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				nodes := uint64(0)
+				start := time.Now()
+				for _, fen := range BenchFens {
+					board, _ := chess.NewBoardFromFen(fen)
+					e := NewEngine()
+					e.SetOption("Threads", threads)
+					nodes += e.Search(board, Limits{Depth: 5}).Nodes
+				}
+				elapsed := time.Since(start)
+				if threads == "1" {
+					single = elapsed
+				}
+				b.ReportMetric(float64(nodes)/elapsed.Seconds(), "nps")
+				b.ReportMetric(float64(elapsed.Milliseconds()), "ms-to-depth")
+				if single > 0 {
+					b.ReportMetric(single.Seconds()/elapsed.Seconds(), "speedup")
+				}
+			}
+		})
+	}
+}
+
+var selectiveOptions = []string{"NullMove", "LMR", "CheckExtension", "Futility", "AspirationWindows"}
+
+func TestSelectiveOptions(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1")
+	for _, name := range selectiveOptions {
+		for _, value := range []string{"true", "false"} {
+			e := NewEngine()
+			if err := e.SetOption(name, value); err != nil {
+				t.Fatal(err)
+			}
+			if result := e.Search(b, Limits{Depth: 4}); result.BestMove.String() != "a0a9" {
+				t.Errorf("%s=%s: best move %v, want a0a9", name, value, result.BestMove)
+			}
+		}
+	}
+	if err := NewEngine().SetOption("LMR", "maybe"); err == nil {
+		t.Error("expected error for invalid check value")
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	options, err := ParseOptions("Hash=32, threads=2,EvalParams=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Hash": "32", "threads": "2", "EvalParams": ""}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("options %v, want %v", options, want)
+	}
+	e := NewEngine()
+	if err := e.SetOptions(options); err != nil {
+		t.Fatal(err)
+	}
+	if o := e.Options(); o.Hash != 32 || o.Threads != 2 {
+		t.Errorf("options not applied: %+v", o)
+	}
+	for _, s := range []string{"Hash", "=1", "Hash=1,"} {
+		if _, err := ParseOptions(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+	if options, err := ParseOptions(""); err != nil || len(options) != 0 {
+		t.Errorf("empty options %v %v", options, err)
+	}
+}
+
+// 在固定节点数下比较打开和关闭选择性搜索时达到的深度：
+// go test -bench SelectiveSearch -benchtime 1x ./engine
+func BenchmarkSelectiveSearch(b *testing.B) {
+	for _, enabled := range []string{"true", "false"} {
+		b.Run(enabled, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				depth := 0
+				for _, fen := range BenchFens {
+					board, _ := chess.NewBoardFromFen(fen)
+					e := NewEngine()
+					for _, name := range selectiveOptions {
+						e.SetOption(name, enabled)
+					}
+					depth += e.Search(board, Limits{Nodes: 100000}).Depth
+				}
+				b.ReportMetric(float64(depth)/float64(len(BenchFens)), "depth")
+			}
+		})
+	}
+}
+
+func TestOwnBook(t *testing.T) {
+	builder := book.NewBuilder(2)
+	move, _ := chess.ParseMove("c3c4")
+	builder.AddGame(chess.NewBoard(), []*chess.Move{move}, chess.RedWins)
+	e := NewEngine()
+	e.SetBook(builder.Book(1))
+	if result := e.Search(chess.NewBoard(), Limits{Depth: 1}); result.Depth != 1 {
+		t.Errorf("book should not be used without OwnBook: %+v", result)
+	}
+	e.SetOption("OwnBook", "true")
+	if result := e.Search(chess.NewBoard(), Limits{Depth: 1}); result.BestMove.String() != "c3c4" || result.Depth != 0 {
+		t.Errorf("expected book move c3c4, got %+v", result)
+	}
+}
+
+func TestTablebase(t *testing.T) {
+	tb := tablebase.New()
+	if _, err := tb.Generate("KRvKA"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := chess.NewBoardFromFen("4k4/4a4/9/9/9/9/9/9/9/R2K5 w")
+	_, dtm, ok := tb.Probe(b)
+	if !ok {
+		t.Fatal("probe failed")
+	}
+	e := NewEngine()
+	e.SetTablebase(tb)
+	result := e.Search(b, Limits{Depth: 3})
+	if result.Score != MateScore-dtm {
+		t.Errorf("got score %d, want %d", result.Score, MateScore-dtm)
+	}
+	if err := e.SetOption("TablebaseDir", "no/such/dir"); err == nil {
+		t.Error("expected error for missing tablebase directory")
+	}
+}
+
+// 残局库的距离将杀远超过 MaxPly 时仍然是杀棋分数，存入置换表前后不变
+func TestLongTablebaseMate(t *testing.T) {
+	const dtm, ply = 253, MaxPly - 1
+	score := MateScore - ply - dtm
+	if got := scoreFromTT(scoreToTT(score, ply), 10); got != MateScore-10-dtm {
+		t.Errorf("score from TT %d, want %d", got, MateScore-10-dtm)
+	}
+	info := Info{Score: MateScore - dtm}
+	if mate := info.Mate(); mate != (dtm+1)/2 {
+		t.Errorf("mate %d, want %d", mate, (dtm+1)/2)
+	}
+	info.Score = -MateScore + dtm - 1
+	if mate := info.Mate(); mate != -(dtm-1)/2 {
+		t.Errorf("mate %d, want %d", mate, -(dtm-1)/2)
+	}
+}
+
+// 黑方只能走回重复局面，红方长将判负，虽然少一个车黑方的分数也应该是胜
+func TestSearchPerpetualCheck(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("4k4/9/9/9/9/9/9/9/9/R2K5 w - - 0 1")
+	for _, s := range []string{"a0a9", "e9e8", "a9a8", "e8e9", "a8a9"} {
+		move, _ := chess.ParseMove(s)
+		b.Push(move)
+	}
+	result := NewEngine().Search(b, Limits{Depth: 3})
+	if result.BestMove == nil || result.BestMove.String() != "e9e8" || result.Score != PerpetualScore {
+		t.Errorf("got %v score %d, want e9e8 score %d", result.BestMove, result.Score, PerpetualScore)
+	}
+}
+
+func TestSearchDeadDraw(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("3ak4/9/9/9/9/9/9/9/9/3KC4 w")
+	if result := NewEngine().Search(b, Limits{Depth: 4}); result.Score != 0 {
+		t.Errorf("got score %d, want 0", result.Score)
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	e := NewEngine()
+	if err := e.SetOption("EvalFile", "../nnue/testdata/material.gcnn"); err != nil {
+		t.Fatal(err)
+	}
+	// 测试网络只计算子力，应该选择白吃的车
+	b, _ := chess.NewBoardFromFen("3k5/9/9/9/9/4r4/9/2c6/4R4/4K4 w")
+	result := e.Search(b, Limits{Depth: 3})
+	if result.BestMove == nil || result.BestMove.String() != "e1e4" || result.Score != 20*(45-22) {
+		t.Errorf("got %v score %d, want e1e4 score %d", result.BestMove, result.Score, 20*(45-22))
+	}
+	if err := e.SetOption("EvalFile", "<empty>"); err != nil || e.network != nil {
+		t.Errorf("EvalFile was not cleared: %v", err)
+	}
+}
+
+func TestEvalParams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "params.txt")
+	if err := os.WriteFile(path, []byte("# test\nvalue rook 1000\npst pawn 9 1 2 3 4 5 4 3 2 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine()
+	if err := e.SetOption("EvalParams", path); err != nil {
+		t.Fatal(err)
+	}
+	if e.params.PieceValues[chess.Rook] != 1000 || e.params.PieceSquareTables[chess.Pawn][9][4] != 5 ||
+		e.params.PieceValues[chess.Knight] != PieceValues[chess.Knight] {
+		t.Errorf("unexpected params %+v", e.params.PieceValues)
+	}
+	if _, err := ReadParams(strings.NewReader("value elephant 10\n")); err == nil {
+		t.Error("expected error for unknown piece")
+	}
+}
+
+func TestSkillLevel(t *testing.T) {
+	play := func(level int, seed int) []string {
+		e := NewEngine()
+		e.SetOption("SkillLevel", strconv.Itoa(level))
+		e.SetOption("Seed", strconv.Itoa(seed))
+		b := chess.NewBoard()
+		var moves []string
+		for i := 0; i < 8; i++ {
+			result := e.Search(b, Limits{})
+			if result.Depth > newSkill(level).depth {
+				t.Fatalf("level %d searched to depth %d", level, result.Depth)
+			}
+			moves = append(moves, result.BestMove.String())
+			b.Push(result.BestMove)
+		}
+		return moves
+	}
+	if a, b := play(3, 42), play(3, 42); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave different games:\n%v\n%v", a, b)
+	}
+	// 低难度在不同种子下应该走出不同的棋
+	openings := map[string]bool{}
+	for seed := 1; seed <= 8; seed++ {
+		openings[strings.Join(play(1, seed), " ")] = true
+	}
+	if len(openings) < 2 {
+		t.Errorf("level 1 always plays the same game")
+	}
+	if err := NewEngine().SetOption("SkillLevel", "0"); err == nil {
+		t.Error("expected error for skill level 0")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	e := NewEngine()
+	e.SetOption("MultiPV", "3")
+	analysis := e.Analyze(chess.NewBoard(), Limits{Depth: 3})
+	lines := map[int]int{}
+	for info := range analysis.Infos {
+		if info.MultiPV < 1 || info.MultiPV > 3 || len(info.PV) == 0 {
+			t.Fatalf("unexpected info %+v", info)
+		}
+		lines[info.Depth]++
+	}
+	if lines[1] != 3 || lines[3] != 3 {
+		t.Errorf("expected 3 lines per depth, got %v", lines)
+	}
+	if result := <-analysis.Result; result.BestMove == nil || result.Depth != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestPonderHit(t *testing.T) {
+	e := NewEngine()
+	results := e.Go(chess.NewBoard(), Limits{Ponder: true, MoveTime: 100 * time.Millisecond})
+	select {
+	case <-results:
+		t.Fatal("ponder search finished before ponderhit")
+	case <-time.After(300 * time.Millisecond):
+	}
+	e.PonderHit()
+	select {
+	case result := <-results:
+		if result.BestMove == nil {
+			t.Error("no best move")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("search did not stop after ponderhit")
+	}
+}
+
+func TestBench(t *testing.T) {
+	first, second := Bench(3), Bench(3)
+	if first.Nodes == 0 || first.Nodes != second.Nodes {
+		t.Fatalf("bench nodes %d and %d", first.Nodes, second.Nodes)
+	}
+	if !reflect.DeepEqual(first.Positions, second.Positions) {
+		t.Error("bench is not deterministic")
+	}
+	nodes := uint64(0)
+	for _, position := range first.Positions {
+		stats := position.Stats
+		nodes += stats.Nodes
+		if stats.Depth != 3 || stats.QNodes > stats.Nodes || stats.TTHits > stats.TTProbes || stats.SelDepth < 3 {
+			t.Errorf("%s: unexpected stats %+v", position.Fen, stats)
+		}
+		if f := stats.FirstMoveCutoffs(); f <= 0 || f > 1 {
+			t.Errorf("%s: first move cutoffs %f", position.Fen, f)
+		}
+	}
+	if nodes != first.Nodes {
+		t.Errorf("position nodes add up to %d, want %d", nodes, first.Nodes)
+	}
+
+	data, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded BenchResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, first) {
+		t.Error("bench result changed after JSON round trip")
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+var PieceValues = [8]int{
+	chess.Pawn:    100,
+	chess.Cannon:  450,
+	chess.Rook:    900,
+	chess.Knight:  400,
+	chess.Bishop:  200,
+	chess.Advisor: 200,
+	chess.King:    0,
+}
+
+// 位置分，从红方视角，下标为 [rank][file]
+var pieceSquareTables = [8][10][9]int{
+	chess.Pawn: {
+		{0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, -2, 0, 4, 0, -2, 0, 0},
+		{2, 0, 8, 0, 8, 0, 8, 0, 2},
+		{6, 12, 18, 18, 20, 18, 18, 12, 6},
+		{10, 20, 30, 34, 40, 34, 30, 20, 10},
+		{14, 26, 42, 60, 80, 60, 42, 26, 14},
+		{18, 36, 56, 80, 120, 80, 56, 36, 18},
+		{0, 3, 6, 9, 12, 9, 6, 3, 0},
+	},
+	chess.Knight: {
+		{0, -4, 0, 0, 0, 0, 0, -4, 0},
+		{0, 2, 4, 4, -10, 4, 4, 2, 0},
+		{4, 2, 8, 8, 4, 8, 8, 2, 4},
+		{2, 6, 8, 6, 10, 6, 8, 6, 2},
+		{4, 12, 16, 14, 12, 14, 16, 12, 4},
+		{6, 16, 14, 18, 16, 18, 14, 16, 6},
+		{8, 24, 18, 24, 20, 24, 18, 24, 8},
+		{12, 14, 16, 20, 18, 20, 16, 14, 12},
+		{4, 10, 28, 16, 8, 16, 28, 10, 4},
+		{4, 8, 16, 12, 4, 12, 16, 8, 4},
+	},
+	chess.Rook: {
+		{-2, 10, 6, 14, 12, 14, 6, 10, -2},
+		{8, 4, 8, 16, 8, 16, 8, 4, 8},
+		{4, 8, 6, 14, 12, 14, 6, 8, 4},
+		{6, 10, 8, 14, 14, 14, 8, 10, 6},
+		{12, 16, 14, 20, 20, 20, 14, 16, 12},
+		{12, 14, 12, 18, 18, 18, 12, 14, 12},
+		{12, 18, 16, 22, 22, 22, 16, 18, 12},
+		{12, 12, 12, 18, 18, 18, 12, 12, 12},
+		{16, 20, 18, 24, 26, 24, 18, 20, 16},
+		{14, 14, 12, 18, 16, 18, 12, 14, 14},
+	},
+	chess.Cannon: {
+		{0, 0, 2, 6, 6, 6, 2, 0, 0},
+		{0, 2, 4, 6, 6, 6, 4, 2, 0},
+		{4, 0, 8, 6, 10, 6, 8, 0, 4},
+		{0, 0, 0, 2, 4, 2, 0, 0, 0},
+		{-2, 0, 4, 2, 6, 2, 4, 0, -2},
+		{0, 0, 0, 2, 8, 2, 0, 0, 0},
+		{0, 0, -2, 4, 10, 4, -2, 0, 0},
+		{2, 2, 0, -10, -8, -10, 0, 2, 2},
+		{2, 2, 0, -4, -14, -4, 0, 2, 2},
+		{6, 4, 0, -10, -12, -10, 0, 4, 6},
+	},
+}
+
+// Params 是手写评估函数的参数，可以从调参工具生成的参数文件中读取
+type Params struct {
+	PieceValues [8]int
+	// PieceSquareTables 是红方视角的位置分，下标为 [pieceType][rank][file]
+	PieceSquareTables [8][10][9]int
+}
+
+// DefaultParams 返回内置的评估参数
+func DefaultParams() *Params {
+	return &Params{PieceValues: PieceValues, PieceSquareTables: pieceSquareTables}
+}
+
+var defaultParams = DefaultParams()
+
+func (p *Params) pieceSquareValue(pieceType uint8, color bool, sq uint8) int {
+	file := chess.SquareFile(sq) - 3
+	rank := chess.SquareRank(sq) - 3
+	if !color {
+		rank = 9 - rank
+	}
+	return p.PieceValues[pieceType] + p.PieceSquareTables[pieceType][rank][file]
+}
+
+// Evaluate 使用参数 p 返回当前局面的静态评估分数，以走子方的视角
+func (p *Params) Evaluate(b *chess.Board) int {
+	score := 0
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		for _, sq := range chess.ScanReversed(b.Pieces(pieceType, chess.Red)) {
+			score += p.pieceSquareValue(pieceType, chess.Red, sq)
+		}
+		for _, sq := range chess.ScanReversed(b.Pieces(pieceType, chess.Black)) {
+			score -= p.pieceSquareValue(pieceType, chess.Black, sq)
+		}
+	}
+	if b.Turn() == chess.Black {
+		return -score
+	}
+	return score
+}
+
+// Evaluate 返回当前局面的静态评估分数，以走子方的视角
+func Evaluate(b *chess.Board) int {
+	return defaultParams.Evaluate(b)
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clysto/gochess/book"
+	"github.com/clysto/gochess/nnue"
+	"github.com/clysto/gochess/tablebase"
+)
+
+type Options struct {
+	Hash    int
+	Threads int
+	MultiPV int
+
+	NullMove          bool
+	LMR               bool
+	CheckExtension    bool
+	Futility          bool
+	AspirationWindows bool
+
+	OwnBook  bool
+	BookFile string
+
+	TablebaseDir string
+	EvalFile     string
+	EvalParams   string
+
+	// SkillLevel 为 1-20，低于 20 时削弱搜索，Seed 不为 0 时随机选择可以复现
+	SkillLevel int
+	Seed       int
+}
+
+var DefaultOptions = Options{
+	Hash:    16,
+	Threads: 1,
+	MultiPV: 1,
+
+	NullMove:          true,
+	LMR:               true,
+	CheckExtension:    true,
+	Futility:          true,
+	AspirationWindows: true,
+
+	SkillLevel: MaxSkillLevel,
+}
+
+// Option 描述引擎的一个可配置选项，供协议层输出
+type Option struct {
+	Name    string
+	Type    string
+	Default string
+	Min     int
+	Max     int
+}
+
+var OptionList = []Option{
+	{Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024},
+	{Name: "Threads", Type: "spin", Default: "1", Min: 1, Max: 64},
+	{Name: "MultiPV", Type: "spin", Default: "1", Min: 1, Max: 16},
+	{Name: "NullMove", Type: "check", Default: "true"},
+	{Name: "LMR", Type: "check", Default: "true"},
+	{Name: "CheckExtension", Type: "check", Default: "true"},
+	{Name: "Futility", Type: "check", Default: "true"},
+	{Name: "AspirationWindows", Type: "check", Default: "true"},
+	{Name: "OwnBook", Type: "check", Default: "false"},
+	{Name: "BookFile", Type: "string", Default: "<empty>"},
+	{Name: "TablebaseDir", Type: "string", Default: "<empty>"},
+	{Name: "EvalFile", Type: "string", Default: "<empty>"},
+	{Name: "EvalParams", Type: "string", Default: "<empty>"},
+	{Name: "SkillLevel", Type: "spin", Default: "20", Min: 1, Max: MaxSkillLevel},
+	{Name: "Seed", Type: "spin", Default: "0", Min: 0, Max: 1<<31 - 1},
+}
+
+func (e *Engine) Options() Options {
+	return e.options
+}
+
+func parseSpin(option Option, value string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("engine: invalid value %q for option %s", value, option.Name)
+	}
+	if n < option.Min || n > option.Max {
+		return 0, fmt.Errorf("engine: value %d for option %s out of range [%d, %d]", n, option.Name, option.Min, option.Max)
+	}
+	return n, nil
+}
+
+func parseCheck(option Option, value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "on":
+		return true, nil
+	case "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("engine: invalid value %q for option %s", value, option.Name)
+}
+
+// SetOption 设置引擎选项，选项名不区分大小写
+func (e *Engine) SetOption(name string, value string) error {
+	var option *Option
+	for i := range OptionList {
+		if strings.EqualFold(OptionList[i].Name, name) {
+			option = &OptionList[i]
+		}
+	}
+	if option == nil {
+		return fmt.Errorf("engine: unknown option %q", name)
+	}
+	n := 0
+	check := false
+	switch option.Type {
+	case "spin":
+		var err error
+		if n, err = parseSpin(*option, value); err != nil {
+			return err
+		}
+	case "check":
+		var err error
+		if check, err = parseCheck(*option, value); err != nil {
+			return err
+		}
+	}
+	switch option.Name {
+	case "Hash":
+		e.options.Hash = n
+		e.tt = newTransTable(n)
+	case "Threads":
+		e.options.Threads = n
+	case "MultiPV":
+		e.options.MultiPV = n
+	case "NullMove":
+		e.options.NullMove = check
+	case "LMR":
+		e.options.LMR = check
+	case "CheckExtension":
+		e.options.CheckExtension = check
+	case "Futility":
+		e.options.Futility = check
+	case "AspirationWindows":
+		e.options.AspirationWindows = check
+	case "OwnBook":
+		e.options.OwnBook = check
+	case "BookFile":
+		value = strings.TrimSpace(value)
+		if value == "" || value == "<empty>" {
+			e.book = nil
+			e.options.BookFile = ""
+			return nil
+		}
+		bk, err := book.Open(value)
+		if err != nil {
+			return err
+		}
+		e.book = bk
+		e.options.BookFile = value
+	case "TablebaseDir":
+		value = strings.TrimSpace(value)
+		if value == "" || value == "<empty>" {
+			e.SetTablebase(nil)
+			e.options.TablebaseDir = ""
+			return nil
+		}
+		tb, err := tablebase.Open(value)
+		if err != nil {
+			return err
+		}
+		e.SetTablebase(tb)
+		e.options.TablebaseDir = value
+	case "EvalFile":
+		value = strings.TrimSpace(value)
+		if value == "" || value == "<empty>" {
+			e.network = nil
+			e.options.EvalFile = ""
+			return nil
+		}
+		n, err := nnue.Load(value)
+		if err != nil {
+			return err
+		}
+		e.network = n
+		e.options.EvalFile = value
+	case "SkillLevel":
+		e.options.SkillLevel = n
+	case "Seed":
+		e.options.Seed = n
+		e.rng = newRand(n)
+	case "EvalParams":
+		value = strings.TrimSpace(value)
+		if value == "" || value == "<empty>" {
+			e.params = defaultParams
+			e.options.EvalParams = ""
+			return nil
+		}
+		p, err := LoadParams(value)
+		if err != nil {
+			return err
+		}
+		e.params = p
+		e.options.EvalParams = value
+	}
+	return nil
+}
+
+// ParseOptions 解析命令行中 "名称=值,名称=值" 格式的引擎选项
+func ParseOptions(s string) (map[string]string, error) {
+	options := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return options, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("engine: invalid option %q", item)
+		}
+		options[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return options, nil
+}
+
+// SetOptions 按名称顺序设置一组选项，遇到第一个错误时返回
+func (e *Engine) SetOptions(options map[string]string) error {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := e.SetOption(name, options[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 参数文件是文本格式，# 开头的行是注释：
+//
+//	value <piece> <n>
+//	pst <piece> <rank> <file0> ... <file8>
+//
+// piece 为 pawn、cannon、rook、knight、bishop、advisor、king，rank 为红方视角的 0-9。
+// 文件中没有给出的参数使用内置的默认值。
+var pieceNames = [8]string{
+	chess.Pawn:    "pawn",
+	chess.Cannon:  "cannon",
+	chess.Rook:    "rook",
+	chess.Knight:  "knight",
+	chess.Bishop:  "bishop",
+	chess.Advisor: "advisor",
+	chess.King:    "king",
+}
+
+func parsePieceName(name string) (uint8, bool) {
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		if pieceNames[pieceType] == name {
+			return pieceType, true
+		}
+	}
+	return 0, false
+}
+
+func LoadParams(path string) (*Params, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadParams(f)
+}
+
+func ReadParams(r io.Reader) (*Params, error) {
+	p := DefaultParams()
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := p.parseLine(fields); err != nil {
+			return nil, fmt.Errorf("engine: params line %d: %v", line, err)
+		}
+	}
+	return p, scanner.Err()
+}
+
+func (p *Params) parseLine(fields []string) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid line")
+	}
+	pieceType, ok := parsePieceName(fields[1])
+	if !ok {
+		return fmt.Errorf("unknown piece %q", fields[1])
+	}
+	var numbers []int
+	for _, field := range fields[2:] {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return err
+		}
+		numbers = append(numbers, n)
+	}
+	switch fields[0] {
+	case "value":
+		if len(numbers) != 1 {
+			return fmt.Errorf("value needs 1 number")
+		}
+		p.PieceValues[pieceType] = numbers[0]
+	case "pst":
+		if len(numbers) != 10 || numbers[0] < 0 || numbers[0] > 9 {
+			return fmt.Errorf("pst needs a rank and 9 numbers")
+		}
+		copy(p.PieceSquareTables[pieceType][numbers[0]][:], numbers[1:])
+	default:
+		return fmt.Errorf("unknown parameter %q", fields[0])
+	}
+	return nil
+}
+
+func (p *Params) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (p *Params) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# gochess evaluation parameters")
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		fmt.Fprintf(bw, "value %s %d\n", pieceNames[pieceType], p.PieceValues[pieceType])
+	}
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		for rank, row := range p.PieceSquareTables[pieceType] {
+			fmt.Fprintf(bw, "pst %s %d", pieceNames[pieceType], rank)
+			for _, n := range row {
+				fmt.Fprintf(bw, " %d", n)
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+	return bw.Flush()
+}
+
+// QuiescenceLeaf 用参数 p 对局面 b 做只考虑吃子的静态搜索，返回主要变例末端的局面和分数。
+// 调参时用末端局面代替原局面，避免在子力交换的中途评估
+func (p *Params) QuiescenceLeaf(b *chess.Board) (*chess.Board, int) {
+	b = b.Copy()
+	var leaf *chess.Board
+	score := p.quiesceLeaf(b, -Infinity, Infinity, 0, &leaf)
+	return leaf, score
+}
+
+func (p *Params) quiesceLeaf(b *chess.Board, alpha int, beta int, ply int, leaf **chess.Board) int {
+	standPat := p.Evaluate(b)
+	if standPat >= beta || ply >= MaxPly {
+		*leaf = b.Copy()
+		return standPat
+	}
+	if standPat > alpha {
+		alpha = standPat
+	}
+	for _, move := range b.PseudoLegalMoves(&chess.BbInBoard, b.OccupiedColor(!b.Turn())) {
+		b.Push(move)
+		if illegal(b) {
+			b.Pop()
+			continue
+		}
+		var childLeaf *chess.Board
+		score := -p.quiesceLeaf(b, -beta, -alpha, ply+1, &childLeaf)
+		b.Pop()
+		if score > alpha {
+			alpha = score
+			*leaf = childLeaf
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	if *leaf == nil {
+		*leaf = b.Copy()
+	}
+	return alpha
+}
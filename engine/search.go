@@ -0,0 +1,810 @@
+package engine
+
+import (
+	"math/bits"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/clysto/gochess/book"
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/nnue"
+	"github.com/clysto/gochess/tablebase"
+)
+
+const (
+	MaxPly    = 64
+	MateScore = 30000
+	Infinity  = 32000
+	// 分数绝对值超过 MateScore-MateWindow 时是杀棋分数。残局库给出的距离将杀最多 253 步，
+	// 加上搜索的步数也不会超出这个范围
+	MateWindow = 512
+	// 长将的一方得到 -PerpetualScore，低于杀棋分数，不会被当作有确定步数的杀棋
+	PerpetualScore = MateScore - 2*MateWindow
+
+	futilityMargin = 150
+	aspirationSize = 50
+)
+
+type Limits struct {
+	Depth    int
+	Nodes    uint64
+	MoveTime time.Duration
+	Infinite bool
+	// Ponder 为 true 时在对方的时间里后台思考，调用 PonderHit 之后才开始按 MoveTime 计时
+	Ponder bool
+}
+
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int
+	Score    int
+	Nodes    uint64
+	Time     time.Duration
+	PV       []*chess.Move
+}
+
+// Mate 返回距离将杀的回合数，正数表示走子方将杀对方，0 表示不是杀棋分数
+func (i *Info) Mate() int {
+	if i.Score > MateScore-MateWindow {
+		return (MateScore - i.Score + 1) / 2
+	} else if i.Score < -MateScore+MateWindow {
+		return -(MateScore + i.Score) / 2
+	}
+	return 0
+}
+
+func (i *Info) NPS() uint64 {
+	ms := uint64(i.Time.Milliseconds())
+	if ms == 0 {
+		return 0
+	}
+	return i.Nodes * 1000 / ms
+}
+
+type Result struct {
+	BestMove   *chess.Move
+	PonderMove *chess.Move
+	Score      int
+	Depth      int
+	Nodes      uint64
+	Stats      Stats
+}
+
+type Engine struct {
+	// OnInfo 在每一层迭代加深完成后调用
+	OnInfo func(info *Info)
+
+	options   Options
+	tt        *transTable
+	stopped   int32
+	searchers []*searcher
+	book      *book.Book
+	rng       *rand.Rand
+	tablebase *tablebase.Tablebase
+	network   *nnue.Network
+	params    *Params
+	// skill 和 noiseKey 在每次搜索开始时根据 SkillLevel 选项设置
+	skill    skill
+	noiseKey uint64
+	// 残局库中最多的棋子数，棋子更多的局面不查表
+	tablebasePieces int
+
+	// pondering 不为 0 时搜索不受时间限制，deadline 是 UnixNano 表示的截止时间，0 表示没有
+	pondering int32
+	deadline  int64
+	moveTime  time.Duration
+	// stream 不为 nil 时搜索信息同时发送到这个通道
+	stream chan<- Info
+}
+
+func NewEngine() *Engine {
+	e := &Engine{
+		options: DefaultOptions,
+		params:  defaultParams,
+		rng:     newRand(0),
+	}
+	e.tt = newTransTable(e.options.Hash)
+	return e
+}
+
+// newRand 返回随机数生成器，seed 为 0 时使用当前时间作为种子
+func newRand(seed int) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(int64(seed)))
+}
+
+// NewGame 清空置换表，在开始新对局时调用
+func (e *Engine) NewGame() {
+	e.tt.clear()
+}
+
+// Stop 通知正在进行的搜索尽快结束
+func (e *Engine) Stop() {
+	atomic.StoreInt32(&e.stopped, 1)
+}
+
+func (e *Engine) isStopped() bool {
+	return atomic.LoadInt32(&e.stopped) != 0
+}
+
+// PonderHit 表示对方走了预测的着法，后台思考转为正常搜索，从现在开始按 MoveTime 计时
+func (e *Engine) PonderHit() {
+	if e.moveTime > 0 {
+		atomic.StoreInt64(&e.deadline, time.Now().Add(e.moveTime).UnixNano())
+	}
+	atomic.StoreInt32(&e.pondering, 0)
+}
+
+func (e *Engine) isPondering() bool {
+	return atomic.LoadInt32(&e.pondering) != 0
+}
+
+// timeLeft 返回距离截止时间的剩余时间，ok 为 false 表示没有时间限制
+func (e *Engine) timeLeft() (left time.Duration, ok bool) {
+	deadline := atomic.LoadInt64(&e.deadline)
+	if deadline == 0 {
+		return 0, false
+	}
+	return time.Until(time.Unix(0, deadline)), true
+}
+
+// begin 在开始搜索之前同步地重置状态，避免 Stop 和 PonderHit 早于搜索线程开始运行
+func (e *Engine) begin(limits Limits, stream chan<- Info) {
+	atomic.StoreInt32(&e.stopped, 0)
+	atomic.StoreInt64(&e.deadline, 0)
+	atomic.StoreInt32(&e.pondering, 0)
+	e.moveTime = limits.MoveTime
+	e.stream = stream
+	if limits.Ponder {
+		atomic.StoreInt32(&e.pondering, 1)
+	} else if limits.MoveTime > 0 {
+		atomic.StoreInt64(&e.deadline, time.Now().Add(limits.MoveTime).UnixNano())
+	}
+}
+
+type searcher struct {
+	e        *Engine
+	id       int
+	board    *chess.Board
+	limits   Limits
+	start    time.Time
+	nodes    uint64
+	selDepth int
+	killers  [MaxPly][2]chess.Move
+	history  [256][256]int
+	pv       [MaxPly + 1][MaxPly + 1]chess.Move
+	pvLength [MaxPly + 1]int
+	// MultiPV 模式下根节点需要跳过的着法
+	excluded []chess.Move
+	// nn 不为 nil 时使用神经网络评估，累加器跟随 board 增量更新
+	nn *nnue.Evaluator
+	// lines 是最后一层完整搜索的根节点候选着法，按分数降序排列
+	lines []rootLine
+	// stats 中的节点数在搜索结束时才从 nodes 复制过来
+	stats Stats
+}
+
+// Search 在局面 b 上搜索最佳着法，b 本身不会被修改
+func (e *Engine) Search(b *chess.Board, limits Limits) Result {
+	e.begin(limits, nil)
+	return e.search(b.Copy(), limits)
+}
+
+// Go 在后台开始搜索，搜索结束后结果会被发送到返回的通道中
+func (e *Engine) Go(b *chess.Board, limits Limits) <-chan Result {
+	e.begin(limits, nil)
+	ch := make(chan Result, 1)
+	board := b.Copy()
+	go func() {
+		ch <- e.search(board, limits)
+	}()
+	return ch
+}
+
+// analysisBuffer 是 Analysis.Infos 的缓冲区大小，读取太慢时多出的信息会被丢弃
+const analysisBuffer = 256
+
+// Analysis 是在后台进行的一次搜索，供界面程序持续显示分析结果
+type Analysis struct {
+	// Infos 依次收到每一层每条变例的搜索信息，搜索结束后关闭
+	Infos <-chan Info
+	// Result 在 Infos 关闭之后收到最终结果
+	Result <-chan Result
+}
+
+// Analyze 在后台开始搜索并通过通道输出搜索信息，通常和 Limits.Infinite、MultiPV 选项一起使用，
+// 用 Stop 结束分析
+func (e *Engine) Analyze(b *chess.Board, limits Limits) *Analysis {
+	infos := make(chan Info, analysisBuffer)
+	results := make(chan Result, 1)
+	e.begin(limits, infos)
+	board := b.Copy()
+	go func() {
+		result := e.search(board, limits)
+		close(infos)
+		results <- result
+	}()
+	return &Analysis{Infos: infos, Result: results}
+}
+
+// SetBook 设置引擎使用的开局库，需要同时打开 OwnBook 选项
+func (e *Engine) SetBook(bk *book.Book) {
+	e.book = bk
+}
+
+// SetNetwork 设置评估使用的神经网络，n 为 nil 时使用手写的评估函数
+func (e *Engine) SetNetwork(n *nnue.Network) {
+	e.network = n
+}
+
+// SetTablebase 设置搜索时查询的残局库，tb 为 nil 时不使用残局库
+func (e *Engine) SetTablebase(tb *tablebase.Tablebase) {
+	e.tablebase = tb
+	e.tablebasePieces = 0
+	if tb != nil {
+		e.tablebasePieces = tb.MaxPieces()
+	}
+}
+
+func (e *Engine) search(b *chess.Board, limits Limits) Result {
+	if e.options.OwnBook && e.book != nil && !limits.Infinite && e.stream == nil {
+		if move := e.book.Pick(b, e.rng); move != nil {
+			return Result{BestMove: move}
+		}
+	}
+	e.skill = newSkill(e.options.SkillLevel)
+	if e.skill.enabled() {
+		limits = e.skill.limit(limits)
+		e.noiseKey = e.rng.Uint64()
+	}
+	start := time.Now()
+	threads := e.options.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	searchers := make([]*searcher, threads)
+	for i := range searchers {
+		searchers[i] = &searcher{
+			e:      e,
+			id:     i,
+			board:  b,
+			limits: limits,
+			start:  start,
+		}
+		if i > 0 {
+			searchers[i].board = b.Copy()
+		}
+		if e.network != nil {
+			searchers[i].nn = e.network.NewEvaluator(searchers[i].board)
+		}
+	}
+	e.searchers = searchers
+
+	// 辅助线程和主线程共享置换表，各自独立地迭代加深，主线程结束后全部停止
+	wg := sync.WaitGroup{}
+	for _, helper := range searchers[1:] {
+		wg.Add(1)
+		go func(helper *searcher) {
+			defer wg.Done()
+			helper.iterate()
+		}(helper)
+	}
+	result := searchers[0].iterate()
+	e.Stop()
+	wg.Wait()
+	if lines := searchers[0].lines; e.skill.enabled() && len(lines) > 0 {
+		move := e.skill.pick(lines, e.rng)
+		if move != *result.BestMove {
+			result.PonderMove = nil
+		}
+		for _, line := range lines {
+			if line.move == move {
+				result.Score = line.score
+			}
+		}
+		result.BestMove = &move
+	}
+	result.Nodes = e.totalNodes()
+	result.Stats.Depth = result.Depth
+	for _, s := range searchers {
+		s.stats.Nodes = s.nodes
+		result.Stats.add(&s.stats)
+	}
+	return result
+}
+
+func (e *Engine) sendInfo(info *Info) {
+	if e.OnInfo != nil {
+		e.OnInfo(info)
+	}
+	if e.stream != nil {
+		select {
+		case e.stream <- *info:
+		default:
+		}
+	}
+}
+
+func (e *Engine) totalNodes() uint64 {
+	nodes := uint64(0)
+	for _, s := range e.searchers {
+		nodes += atomic.LoadUint64(&s.nodes)
+	}
+	return nodes
+}
+
+// iterate 对根局面进行迭代加深搜索，只有主线程 (id 为 0) 输出信息
+func (s *searcher) iterate() Result {
+	e := s.e
+	limits := s.limits
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth >= MaxPly {
+		maxDepth = MaxPly - 1
+	}
+
+	result := Result{}
+	legal := s.board.LegalMoves()
+	if len(legal) == 0 {
+		return result
+	}
+	result.BestMove = legal[0]
+	multiPV := e.options.MultiPV
+	if e.skill.enabled() && multiPV < skillCandidates {
+		multiPV = skillCandidates
+	}
+	if multiPV > len(legal) {
+		multiPV = len(legal)
+	}
+	if s.id > 0 {
+		multiPV = 1
+	}
+
+	// 一半的辅助线程从更深一层开始，让各线程搜索的深度错开
+	for depth := 1 + s.id%2; depth <= maxDepth; depth++ {
+		s.excluded = s.excluded[:0]
+		score := 0
+		var lines []rootLine
+		for pvIndex := 1; pvIndex <= multiPV; pvIndex++ {
+			s.selDepth = 0
+			lineScore := s.searchRoot(depth, result.Score, multiPV == 1 && depth >= 4)
+			if e.isStopped() && (depth > 1 || pvIndex > 1) {
+				break
+			}
+			if s.pvLength[0] == 0 {
+				break
+			}
+			if pvIndex == 1 {
+				score = lineScore
+				best := s.pv[0][0]
+				result.BestMove = &best
+				result.PonderMove = nil
+				if s.pvLength[0] > 1 {
+					ponder := s.pv[0][1]
+					result.PonderMove = &ponder
+				}
+				result.Score = score
+				result.Depth = depth
+			}
+			s.excluded = append(s.excluded, s.pv[0][0])
+			lines = append(lines, rootLine{move: s.pv[0][0], score: lineScore})
+			if (e.OnInfo != nil || e.stream != nil) && s.id == 0 {
+				info := &Info{
+					Depth:    depth,
+					SelDepth: s.selDepth,
+					MultiPV:  pvIndex,
+					Score:    lineScore,
+					Nodes:    e.totalNodes(),
+					Time:     time.Since(s.start),
+				}
+				for i := 0; i < s.pvLength[0]; i++ {
+					move := s.pv[0][i]
+					info.PV = append(info.PV, &move)
+				}
+				e.sendInfo(info)
+			}
+		}
+		if e.isStopped() {
+			break
+		}
+		s.lines = lines
+		// 已经找到杀棋，没有必要继续加深
+		if !limits.Infinite && !e.isPondering() && multiPV == 1 && (score > MateScore-depth || score < -MateScore+depth) {
+			break
+		}
+		// 剩余时间不足以完成下一层
+		if left, ok := e.timeLeft(); ok && left < e.moveTime/2 {
+			break
+		}
+	}
+	return result
+}
+
+// searchRoot 在上一层分数附近使用窄窗口搜索，失败时逐步放宽窗口
+func (s *searcher) searchRoot(depth int, previous int, aspiration bool) int {
+	if !aspiration || !s.e.options.AspirationWindows {
+		return s.negamax(depth, 0, -Infinity, Infinity)
+	}
+	delta := aspirationSize
+	alpha, beta := previous-delta, previous+delta
+	for {
+		score := s.negamax(depth, 0, alpha, beta)
+		if s.e.isStopped() {
+			return score
+		}
+		if score > alpha && score < beta {
+			return score
+		}
+		delta *= 4
+		if delta > PieceValues[chess.Rook] {
+			alpha, beta = -Infinity, Infinity
+		} else if score <= alpha {
+			alpha = previous - delta
+		} else {
+			beta = previous + delta
+		}
+	}
+}
+
+func (s *searcher) addNode() {
+	if atomic.AddUint64(&s.nodes, 1)&1023 != 0 {
+		return
+	}
+	if s.limits.Nodes > 0 && s.e.totalNodes() >= s.limits.Nodes {
+		s.e.Stop()
+	}
+	if left, ok := s.e.timeLeft(); ok && left <= 0 {
+		s.e.Stop()
+	}
+}
+
+// illegal 判断刚走完的一步是否让自己的将帅处于被攻击的状态
+func illegal(b *chess.Board) bool {
+	king := b.King(!b.Turn())
+	return king == 0 || b.IsAttackedBy(b.Turn(), king) || b.KingsFacing()
+}
+
+func (s *searcher) orderMoves(moves []*chess.Move, ttMove chess.Move, ply int) {
+	scores := make(map[*chess.Move]int, len(moves))
+	for _, move := range moves {
+		switch {
+		case *move == ttMove:
+			scores[move] = 1 << 30
+		case s.board.IsCapture(move):
+			victim := s.board.PieceTypeAt(move.ToSquare)
+			attacker := s.board.PieceTypeAt(move.FromSquare)
+			scores[move] = 1<<24 + PieceValues[victim]*16 - PieceValues[attacker]/16
+		case ply < MaxPly && *move == s.killers[ply][0]:
+			scores[move] = 1<<22 + 1
+		case ply < MaxPly && *move == s.killers[ply][1]:
+			scores[move] = 1 << 22
+		default:
+			scores[move] = s.history[move.FromSquare][move.ToSquare]
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return scores[moves[i]] > scores[moves[j]]
+	})
+}
+
+func (s *searcher) negamax(depth int, ply int, alpha int, beta int) int {
+	s.pvLength[ply] = ply
+	if ply >= MaxPly {
+		return s.evaluate()
+	}
+	if ply > 0 && s.board.RepetitionCount() > 1 {
+		// 和对局中一样，一方长将判负，其他重复局面按和棋计算
+		if checker, ok := s.board.PerpetualChecker(); ok {
+			if checker == s.board.Turn() {
+				return -PerpetualScore
+			}
+			return PerpetualScore
+		}
+		return 0
+	}
+	if ply > 0 {
+		if score, ok := s.probeTablebase(ply); ok {
+			return score
+		}
+		// 双方各有士象、一方再多一个进攻子时最多 11 个棋子，棋子更多时不可能是必和局面
+		if pieceCount(s.board) <= 11 && s.board.IsDeadDraw() {
+			return 0
+		}
+	}
+	if depth <= 0 {
+		return s.quiesce(ply, alpha, beta)
+	}
+
+	s.addNode()
+	if s.e.isStopped() {
+		return 0
+	}
+
+	pvNode := beta-alpha > 1
+	key := s.board.Hash()
+	ttMove := chess.NullMove
+	s.stats.TTProbes++
+	if entry, ok := s.e.tt.probe(key); ok {
+		s.stats.TTHits++
+		ttMove = entry.move
+		score := scoreFromTT(int(entry.score), ply)
+		if !pvNode && ply > 0 && int(entry.depth) >= depth {
+			if entry.bound == boundExact ||
+				(entry.bound == boundLower && score >= beta) ||
+				(entry.bound == boundUpper && score <= alpha) {
+				s.stats.TTCutoffs++
+				return score
+			}
+		}
+	}
+
+	options := &s.e.options
+	inCheck := s.board.IsCheck()
+	if inCheck && options.CheckExtension && ply < MaxPly/2 {
+		depth++
+	}
+	staticEval := -Infinity
+	if !inCheck {
+		staticEval = s.evaluate()
+	}
+
+	// 空着裁剪：让对方连走两步仍然不能低于 beta，就认为当前局面足够好。
+	// 进攻子力太少时容易出现等着的局面，这时不做空着裁剪
+	if options.NullMove && !pvNode && !inCheck && ply > 0 && depth >= 3 &&
+		staticEval >= beta && s.lastMove() != chess.NullMove && attackers(s.board, s.board.Turn()) >= 2 {
+		reduction := 2
+		if depth > 6 {
+			reduction = 3
+		}
+		s.pushNull()
+		score := -s.negamax(depth-1-reduction, ply+1, -beta, -beta+1)
+		s.pop()
+		if s.e.isStopped() {
+			return 0
+		}
+		if score >= beta {
+			s.stats.NullCutoffs++
+			if score > MateScore-MateWindow {
+				score = beta
+			}
+			return score
+		}
+	}
+
+	// 深度较浅并且静态评估远低于 alpha 时，不再搜索不吃子的着法
+	futile := options.Futility && !pvNode && !inCheck && depth <= 3 &&
+		staticEval+futilityMargin*depth <= alpha && alpha > -MateScore+MateWindow
+
+	moves := s.board.PseudoLegalMoves(&chess.BbInBoard, &chess.BbInBoard)
+	s.orderMoves(moves, ttMove, ply)
+
+	bestScore := -Infinity
+	bestMove := chess.NullMove
+	bound := boundUpper
+	legalMoves := 0
+	for _, move := range moves {
+		if ply == 0 && s.isExcluded(move) {
+			continue
+		}
+		capture := s.board.IsCapture(move)
+		s.push(move)
+		if illegal(s.board) {
+			s.pop()
+			continue
+		}
+		givesCheck := s.board.IsCheck()
+		quiet := !capture && !givesCheck && *move != ttMove
+		if futile && quiet && legalMoves > 0 {
+			s.pop()
+			continue
+		}
+		legalMoves++
+		var score int
+		if legalMoves == 1 {
+			score = -s.negamax(depth-1, ply+1, -beta, -alpha)
+		} else {
+			// 排序靠后的安静着法先用减少的深度搜索，分数超过 alpha 再正常搜索
+			reduction := 0
+			if options.LMR && quiet && !inCheck && depth >= 3 && legalMoves > 3 {
+				reduction = 1
+				if depth > 5 && legalMoves > 10 {
+					reduction = 2
+				}
+			}
+			score = -s.negamax(depth-1-reduction, ply+1, -alpha-1, -alpha)
+			if reduction > 0 && score > alpha {
+				score = -s.negamax(depth-1, ply+1, -alpha-1, -alpha)
+			}
+			if score > alpha && score < beta {
+				score = -s.negamax(depth-1, ply+1, -beta, -alpha)
+			}
+		}
+		s.pop()
+		if s.e.isStopped() {
+			return 0
+		}
+
+		if score > bestScore {
+			bestScore = score
+			bestMove = *move
+		}
+		if score > alpha {
+			alpha = score
+			bound = boundExact
+			s.pv[ply][ply] = *move
+			for i := ply + 1; i < s.pvLength[ply+1]; i++ {
+				s.pv[ply][i] = s.pv[ply+1][i]
+			}
+			s.pvLength[ply] = s.pvLength[ply+1]
+		}
+		if alpha >= beta {
+			bound = boundLower
+			s.stats.cutoff(legalMoves - 1)
+			if !capture {
+				if s.killers[ply][0] != *move {
+					s.killers[ply][1] = s.killers[ply][0]
+					s.killers[ply][0] = *move
+				}
+				s.history[move.FromSquare][move.ToSquare] += depth * depth
+			}
+			break
+		}
+	}
+
+	if legalMoves == 0 {
+		// 象棋中无论将死还是困毙都判负
+		return -MateScore + ply
+	}
+	if ply > 0 || len(s.excluded) == 0 {
+		s.e.tt.store(key, bestMove, scoreToTT(bestScore, ply), depth, bound)
+	}
+	return bestScore
+}
+
+// probeTablebase 在残局库中查询当前局面，胜负局面的分数按距离将杀的步数计算
+func (s *searcher) probeTablebase(ply int) (int, bool) {
+	e := s.e
+	if e.tablebase == nil || pieceCount(s.board) > e.tablebasePieces {
+		return 0, false
+	}
+	wdl, dtm, ok := e.tablebase.Probe(s.board)
+	if !ok {
+		return 0, false
+	}
+	s.addNode()
+	switch wdl {
+	case tablebase.Win:
+		return MateScore - ply - dtm, true
+	case tablebase.Loss:
+		return -MateScore + ply + dtm, true
+	}
+	return 0, true
+}
+
+func pieceCount(b *chess.Board) int {
+	n := 0
+	for _, word := range b.Occupied() {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+func (s *searcher) push(move *chess.Move) {
+	if s.nn != nil {
+		s.nn.Push(s.board, move)
+	}
+	s.board.Push(move)
+}
+
+func (s *searcher) pushNull() {
+	if s.nn != nil {
+		s.nn.Push(s.board, &chess.NullMove)
+	}
+	s.board.PushNull()
+}
+
+func (s *searcher) pop() {
+	if s.nn != nil {
+		s.nn.Pop()
+	}
+	s.board.Pop()
+}
+
+func (s *searcher) evaluate() int {
+	if s.nn != nil {
+		return s.nn.Evaluate(s.board.Turn()) + s.e.skill.noiseFor(s.board.Hash(), s.e.noiseKey)
+	}
+	return s.e.params.Evaluate(s.board) + s.e.skill.noiseFor(s.board.Hash(), s.e.noiseKey)
+}
+
+func (s *searcher) lastMove() chess.Move {
+	if move := s.board.Peek(); move != nil {
+		return *move
+	}
+	return chess.NullMove
+}
+
+// attackers 返回一方车、马、炮的数量
+func attackers(b *chess.Board, color bool) int {
+	n := 0
+	for _, pieceType := range []uint8{chess.Rook, chess.Knight, chess.Cannon} {
+		n += len(chess.ScanReversed(b.Pieces(pieceType, color)))
+	}
+	return n
+}
+
+func (s *searcher) isExcluded(move *chess.Move) bool {
+	for _, excluded := range s.excluded {
+		if *move == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *searcher) quiesce(ply int, alpha int, beta int) int {
+	s.addNode()
+	s.stats.QNodes++
+	if s.e.isStopped() {
+		return 0
+	}
+	if ply > s.selDepth {
+		s.selDepth = ply
+		if ply > s.stats.SelDepth {
+			s.stats.SelDepth = ply
+		}
+	}
+	if ply >= MaxPly {
+		return s.evaluate()
+	}
+
+	inCheck := s.board.IsCheck()
+	bestScore := -Infinity
+	var moves []*chess.Move
+	if inCheck {
+		moves = s.board.PseudoLegalMoves(&chess.BbInBoard, &chess.BbInBoard)
+	} else {
+		bestScore = s.evaluate()
+		if bestScore >= beta {
+			return bestScore
+		}
+		if bestScore > alpha {
+			alpha = bestScore
+		}
+		moves = s.board.PseudoLegalMoves(&chess.BbInBoard, s.board.OccupiedColor(!s.board.Turn()))
+	}
+	s.orderMoves(moves, chess.NullMove, MaxPly)
+
+	legalMoves := 0
+	for _, move := range moves {
+		s.push(move)
+		if illegal(s.board) {
+			s.pop()
+			continue
+		}
+		legalMoves++
+		score := -s.quiesce(ply+1, -beta, -alpha)
+		s.pop()
+		if s.e.isStopped() {
+			return 0
+		}
+		if score > bestScore {
+			bestScore = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	if inCheck && legalMoves == 0 {
+		return -MateScore + ply
+	}
+	return bestScore
+}
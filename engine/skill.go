@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"math/rand"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// MaxSkillLevel 是最高难度，这时不对搜索做任何限制
+const MaxSkillLevel = 20
+
+// skillCandidates 是降低难度时根节点保留的候选着法数
+const skillCandidates = 4
+
+// skill 描述某个难度等级对搜索的削弱：限制深度和节点数、给评估加上噪声，
+// 并且按一定概率选择不是最好的候选着法
+type skill struct {
+	level int
+	depth int
+	nodes uint64
+	noise int
+}
+
+func newSkill(level int) skill {
+	if level >= MaxSkillLevel {
+		return skill{level: MaxSkillLevel}
+	}
+	if level < 1 {
+		level = 1
+	}
+	return skill{
+		level: level,
+		depth: 1 + level/2,
+		nodes: 1024 << uint(level/2),
+		noise: (MaxSkillLevel - level) * 10,
+	}
+}
+
+func (sk skill) enabled() bool {
+	return sk.level < MaxSkillLevel
+}
+
+// limit 在 limits 的基础上加上难度对应的深度和节点数上限
+func (sk skill) limit(limits Limits) Limits {
+	if !sk.enabled() {
+		return limits
+	}
+	if limits.Depth <= 0 || limits.Depth > sk.depth {
+		limits.Depth = sk.depth
+	}
+	if limits.Nodes == 0 || limits.Nodes > sk.nodes {
+		limits.Nodes = sk.nodes
+	}
+	return limits
+}
+
+// rootLine 是根节点的一个候选着法和它的分数
+type rootLine struct {
+	move  chess.Move
+	score int
+}
+
+// pick 从按分数降序排列的候选着法中选择一个，难度越低越容易选到分数差的着法
+func (sk skill) pick(lines []rootLine, rng *rand.Rand) chess.Move {
+	weakness := 120 - 2*sk.level
+	top := lines[0].score
+	delta := top - lines[len(lines)-1].score
+	if delta > PieceValues[chess.Pawn] {
+		delta = PieceValues[chess.Pawn]
+	}
+	best, bestScore := lines[0].move, -Infinity
+	for _, line := range lines {
+		push := (weakness*(top-line.score) + delta*rng.Intn(weakness)) / 128
+		if line.score+push >= bestScore {
+			best, bestScore = line.move, line.score+push
+		}
+	}
+	return best
+}
+
+// noiseFor 返回局面的评估噪声，同一局面在一次搜索中的噪声相同，避免置换表中的分数互相矛盾
+func (sk skill) noiseFor(hash uint64, key uint64) int {
+	if sk.noise == 0 {
+		return 0
+	}
+	x := hash ^ key
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return int(x%uint64(2*sk.noise+1)) - sk.noise
+}
@@ -0,0 +1,59 @@
+package engine
+
+// CutoffSlots 是按着法序号统计 beta 截断时的分组数，最后一组包括序号更大的所有着法
+const CutoffSlots = 8
+
+// Stats 是一次搜索的统计数据，所有线程的数据会累加在一起。
+// 单线程搜索的统计数据是确定的，可以编码为 JSON 用来检查搜索行为有没有意外的变化
+type Stats struct {
+	// Nodes 包括 QNodes
+	Nodes  uint64 `json:"nodes"`
+	QNodes uint64 `json:"qnodes"`
+	// TTProbes 是查询置换表的次数，TTHits 是找到条目的次数，TTCutoffs 是直接返回置换表分数的次数
+	TTProbes  uint64 `json:"tt_probes"`
+	TTHits    uint64 `json:"tt_hits"`
+	TTCutoffs uint64 `json:"tt_cutoffs"`
+	// NullCutoffs 是空着裁剪成功的次数
+	NullCutoffs uint64 `json:"null_cutoffs"`
+	// Cutoffs[i] 是第 i+1 个合法着法产生 beta 截断的次数
+	Cutoffs  [CutoffSlots]uint64 `json:"cutoffs"`
+	Depth    int                 `json:"depth"`
+	SelDepth int                 `json:"seldepth"`
+}
+
+// FirstMoveCutoffs 返回第一个着法产生的截断占所有截断的比例，用来衡量着法排序的好坏
+func (s *Stats) FirstMoveCutoffs() float64 {
+	total := uint64(0)
+	for _, n := range s.Cutoffs {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Cutoffs[0]) / float64(total)
+}
+
+func (s *Stats) cutoff(moveIndex int) {
+	if moveIndex >= CutoffSlots {
+		moveIndex = CutoffSlots - 1
+	}
+	s.Cutoffs[moveIndex]++
+}
+
+func (s *Stats) add(o *Stats) {
+	s.Nodes += o.Nodes
+	s.QNodes += o.QNodes
+	s.TTProbes += o.TTProbes
+	s.TTHits += o.TTHits
+	s.TTCutoffs += o.TTCutoffs
+	s.NullCutoffs += o.NullCutoffs
+	for i := range s.Cutoffs {
+		s.Cutoffs[i] += o.Cutoffs[i]
+	}
+	if o.Depth > s.Depth {
+		s.Depth = o.Depth
+	}
+	if o.SelDepth > s.SelDepth {
+		s.SelDepth = o.SelDepth
+	}
+}
@@ -0,0 +1,24 @@
+package engine
+
+import (
+	"time"
+)
+
+// TimeForMove 根据剩余时间、每步加秒和距离下一个时限的步数估算本步的用时
+func TimeForMove(remaining time.Duration, increment time.Duration, movesToGo int) time.Duration {
+	if remaining <= 0 {
+		return 0
+	}
+	if movesToGo <= 0 || movesToGo > 30 {
+		movesToGo = 30
+	}
+	t := remaining/time.Duration(movesToGo) + increment*3/4
+	// 保留一部分时间应对通信延迟
+	if max := remaining - 50*time.Millisecond; t > max {
+		t = max
+	}
+	if t < 10*time.Millisecond {
+		t = 10 * time.Millisecond
+	}
+	return t
+}
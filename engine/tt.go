@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"sync/atomic"
+
+	"github.com/clysto/gochess/chess"
+)
+
+const (
+	boundExact uint8 = iota + 1
+	boundLower
+	boundUpper
+)
+
+type ttEntry struct {
+	move  chess.Move
+	score int16
+	depth int8
+	bound uint8
+}
+
+func (e ttEntry) pack() uint64 {
+	return uint64(e.move.FromSquare) |
+		uint64(e.move.ToSquare)<<8 |
+		uint64(uint16(e.score))<<16 |
+		uint64(uint8(e.depth))<<32 |
+		uint64(e.bound)<<40
+}
+
+func unpackEntry(data uint64) ttEntry {
+	return ttEntry{
+		move:  chess.Move{FromSquare: uint8(data), ToSquare: uint8(data >> 8)},
+		score: int16(uint16(data >> 16)),
+		depth: int8(uint8(data >> 32)),
+		bound: uint8(data >> 40),
+	}
+}
+
+// ttSlot 中保存 key^data，读取时如果 key 对不上说明数据被其他线程改写了一半，
+// 这样多个线程不需要加锁就能共享置换表
+type ttSlot struct {
+	key  uint64
+	data uint64
+}
+
+type transTable struct {
+	slots []ttSlot
+	mask  uint64
+}
+
+const ttSlotSize = 16
+
+func newTransTable(sizeMB int) *transTable {
+	n := uint64(1)
+	for n*2*ttSlotSize <= uint64(sizeMB)<<20 {
+		n *= 2
+	}
+	return &transTable{
+		slots: make([]ttSlot, n),
+		mask:  n - 1,
+	}
+}
+
+func (t *transTable) clear() {
+	for i := range t.slots {
+		atomic.StoreUint64(&t.slots[i].key, 0)
+		atomic.StoreUint64(&t.slots[i].data, 0)
+	}
+}
+
+func (t *transTable) probe(key uint64) (ttEntry, bool) {
+	slot := &t.slots[key&t.mask]
+	data := atomic.LoadUint64(&slot.data)
+	if atomic.LoadUint64(&slot.key)^data != key {
+		return ttEntry{}, false
+	}
+	e := unpackEntry(data)
+	return e, e.bound != 0
+}
+
+func (t *transTable) store(key uint64, move chess.Move, score int, depth int, bound uint8) {
+	slot := &t.slots[key&t.mask]
+	data := atomic.LoadUint64(&slot.data)
+	sameKey := atomic.LoadUint64(&slot.key)^data == key
+	old := unpackEntry(data)
+	// 同一局面没有新着法时保留原来的着法
+	if sameKey && move == chess.NullMove {
+		move = old.move
+	}
+	if !sameKey || depth >= int(old.depth) || bound == boundExact {
+		data = ttEntry{move: move, score: int16(score), depth: int8(depth), bound: bound}.pack()
+		atomic.StoreUint64(&slot.key, key^data)
+		atomic.StoreUint64(&slot.data, data)
+	}
+}
+
+// 将杀分数存入置换表时换算成相对于当前节点的距离
+func scoreToTT(score int, ply int) int {
+	if score > MateScore-MateWindow {
+		return score + ply
+	} else if score < -MateScore+MateWindow {
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTT(score int, ply int) int {
+	if score > MateScore-MateWindow {
+		return score - ply
+	} else if score < -MateScore+MateWindow {
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,213 @@
+// Package epd 读写 EPD 测试局面集。每行是 FEN 的前几个字段加上一组操作，例如
+//
+//	2bakab2/9/4c4/p3p3p/9/9/P3P3P/4C4/9/2BAKAB2 w - - bm e2e6; id "test 1";
+//
+// bm 是最佳着法，am 是应该避免的着法，着法可以用 ICCS 坐标、WXF 或者中文记谱。
+package epd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+)
+
+var ErrSyntax = errors.New("epd: syntax error")
+
+// Operation 是一个操作码和它的操作数
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// Position 是 EPD 中的一个局面
+type Position struct {
+	Board      *chess.Board
+	Operations []Operation
+}
+
+// Operands 返回操作码的操作数，没有这个操作时返回 nil
+func (p *Position) Operands(opcode string) []string {
+	for _, op := range p.Operations {
+		if op.Opcode == opcode {
+			return op.Operands
+		}
+	}
+	return nil
+}
+
+// ID 返回 id 操作给出的局面名称
+func (p *Position) ID() string {
+	if operands := p.Operands("id"); len(operands) > 0 {
+		return operands[0]
+	}
+	return ""
+}
+
+// BestMoves 返回 bm 操作给出的最佳着法
+func (p *Position) BestMoves() ([]*chess.Move, error) {
+	return p.moves("bm")
+}
+
+// AvoidMoves 返回 am 操作给出的应该避免的着法
+func (p *Position) AvoidMoves() ([]*chess.Move, error) {
+	return p.moves("am")
+}
+
+func (p *Position) moves(opcode string) ([]*chess.Move, error) {
+	var moves []*chess.Move
+	for _, s := range p.Operands(opcode) {
+		move, err := p.Board.ParseMove(s)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// String 返回 EPD 格式的一行，操作数中有空格或者分号时加上引号
+func (p *Position) String() string {
+	fields := strings.Fields(p.Board.Fen())
+	var sb strings.Builder
+	sb.WriteString(strings.Join(fields[:4], " "))
+	for _, op := range p.Operations {
+		sb.WriteString(" " + op.Opcode)
+		for _, operand := range op.Operands {
+			if operand == "" || strings.ContainsAny(operand, " ;\"") {
+				operand = strconv.Quote(operand)
+			}
+			sb.WriteString(" " + operand)
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// Parse 解析 EPD 的一行。局面部分是 FEN 的棋盘和走子方，之后的 - 和数字字段会被忽略
+func Parse(line string) (*Position, error) {
+	fields, rest := splitFields(line, 2)
+	if len(fields) < 2 {
+		return nil, ErrSyntax
+	}
+	b, err := chess.NewBoardFromFen(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+	// 跳过 FEN 中的其他字段，操作码不会以 - 和数字开头
+	for {
+		s := strings.TrimLeft(rest, " \t")
+		if s == "" || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+			rest = s
+			break
+		}
+		_, rest = splitFields(s, 1)
+	}
+	p := &Position{Board: b}
+	for rest != "" {
+		op, next, err := parseOperation(rest)
+		if err != nil {
+			return nil, err
+		}
+		p.Operations = append(p.Operations, op)
+		rest = strings.TrimLeft(next, " \t")
+	}
+	return p, nil
+}
+
+// splitFields 从 s 的开头取出 n 个空白分隔的字段
+func splitFields(s string, n int) ([]string, string) {
+	var fields []string
+	for len(fields) < n {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		fields = append(fields, s[:end])
+		s = s[end:]
+	}
+	return fields, s
+}
+
+// parseOperation 读取一个以分号结束的操作，操作数可以是带引号的字符串
+func parseOperation(s string) (Operation, string, error) {
+	var op Operation
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		switch {
+		case s == "":
+			// 最后一个操作可以省略分号
+		case s[0] == ';':
+			s = s[1:]
+		case s[0] == '"':
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return op, "", ErrSyntax
+			}
+			word, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return op, "", ErrSyntax
+			}
+			words = append(words, word)
+			s = s[end+1:]
+			continue
+		default:
+			end := strings.IndexAny(s, " \t;")
+			if end < 0 {
+				end = len(s)
+			}
+			words = append(words, s[:end])
+			s = s[end:]
+			continue
+		}
+		break
+	}
+	if len(words) == 0 {
+		return op, "", ErrSyntax
+	}
+	op.Opcode, op.Operands = words[0], words[1:]
+	return op, s, nil
+}
+
+// ReadAll 读取所有局面，跳过空行和 # 开头的注释
+func ReadAll(r io.Reader) ([]*Position, error) {
+	var positions []*Position
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		p, err := Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("epd: line %d: %v", line, err)
+		}
+		positions = append(positions, p)
+	}
+	return positions, scanner.Err()
+}
+
+func Load(path string) ([]*Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAll(f)
+}
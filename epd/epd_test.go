@@ -0,0 +1,74 @@
+package epd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/engine"
+)
+
+const mate = "3k5/R8/9/9/9/9/9/9/9/1R3K3 w"
+
+func TestParse(t *testing.T) {
+	p, err := Parse(mate + ` - - 0 1 bm b0b9 B0-B9; am a8a7; id "mate; in one"; c0 "say \"hi\"" x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Operation{
+		{"bm", []string{"b0b9", "B0-B9"}},
+		{"am", []string{"a8a7"}},
+		{"id", []string{"mate; in one"}},
+		{"c0", []string{`say "hi"`, "x"}},
+	}
+	if !reflect.DeepEqual(p.Operations, want) {
+		t.Fatalf("operations %q", p.Operations)
+	}
+	if p.ID() != "mate; in one" {
+		t.Errorf("id %q", p.ID())
+	}
+	best, err := p.BestMoves()
+	if err != nil || len(best) != 2 || best[0].String() != "b0b9" || *best[0] != *best[1] {
+		t.Errorf("bm %v %v", best, err)
+	}
+	again, err := Parse(p.String())
+	if err != nil || !reflect.DeepEqual(again.Operations, p.Operations) || again.Board.Fen() != p.Board.Fen() {
+		t.Errorf("round trip %q: %v", p.String(), err)
+	}
+
+	for _, line := range []string{"", mate + ` id "open`, "3k5/9 w bm a0a1"} {
+		if _, err := Parse(line); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+	p, _ = Parse(mate + " bm i0i9")
+	if _, err := p.BestMoves(); err == nil {
+		t.Error("expected error for illegal best move")
+	}
+}
+
+func TestRun(t *testing.T) {
+	suite := `# 杀法
+` + mate + ` bm 车八进九; id "mate";
+` + mate + ` am b0b9; id "avoid";
+` + mate + ` id "no target";
+`
+	positions, err := ReadAll(strings.NewReader(suite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	summary := Run(engine.NewEngine(), positions, engine.Limits{Depth: 3}, func(r *Result) {
+		ids = append(ids, r.ID)
+	})
+	if !reflect.DeepEqual(ids, []string{"mate", "avoid", "no target"}) {
+		t.Errorf("results %v", ids)
+	}
+	r := summary.Results
+	if !r[0].Solved || r[0].Move != "b0b9" || r[0].FoundDepth == 0 {
+		t.Errorf("mate not found: %+v", r[0])
+	}
+	if r[1].Solved || r[2].Solved || summary.Solved != 1 || summary.Positions != 3 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
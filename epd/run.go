@@ -0,0 +1,127 @@
+package epd
+
+import (
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+// Result 是引擎在一个局面上的搜索结果
+type Result struct {
+	ID     string        `json:"id"`
+	Fen    string        `json:"fen"`
+	Best   []string      `json:"bm,omitempty"`
+	Avoid  []string      `json:"am,omitempty"`
+	Move   string        `json:"move"`
+	Score  int           `json:"score"`
+	Depth  int           `json:"depth"`
+	Nodes  uint64        `json:"nodes"`
+	Time   time.Duration `json:"time_ns"`
+	Solved bool          `json:"solved"`
+	// FoundDepth 和 FoundTime 是找到正确着法并且之后不再改变时的深度和用时，没有解出时为 0
+	FoundDepth int           `json:"found_depth,omitempty"`
+	FoundTime  time.Duration `json:"found_time_ns,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Summary 是整个测试集的结果
+type Summary struct {
+	DepthLimit int           `json:"depth_limit,omitempty"`
+	MoveTime   time.Duration `json:"movetime_ns,omitempty"`
+	NodeLimit  uint64        `json:"node_limit,omitempty"`
+	Positions  int           `json:"positions"`
+	Solved     int           `json:"solved"`
+	Nodes      uint64        `json:"nodes"`
+	Time       time.Duration `json:"time_ns"`
+	Results    []Result      `json:"results"`
+}
+
+// solved 判断着法是否在 bm 中并且不在 am 中，局面没有 bm 和 am 时总是返回 false
+func solved(move *chess.Move, best []*chess.Move, avoid []*chess.Move) bool {
+	if move == nil || (len(best) == 0 && len(avoid) == 0) {
+		return false
+	}
+	contains := func(moves []*chess.Move) bool {
+		for _, m := range moves {
+			if *m == *move {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(best) == 0 || contains(best)) && !contains(avoid)
+}
+
+func moveStrings(moves []*chess.Move) []string {
+	var s []string
+	for _, move := range moves {
+		s = append(s, move.String())
+	}
+	return s
+}
+
+// Search 用 e 搜索一个局面，搜索之前会清空引擎的置换表等状态
+func Search(e *engine.Engine, p *Position, limits engine.Limits) Result {
+	r := Result{ID: p.ID(), Fen: p.Board.Fen()}
+	best, err := p.BestMoves()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	avoid, err := p.AvoidMoves()
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Best, r.Avoid = moveStrings(best), moveStrings(avoid)
+
+	e.NewGame()
+	onInfo := e.OnInfo
+	defer func() { e.OnInfo = onInfo }()
+	e.OnInfo = func(info *engine.Info) {
+		if len(info.PV) == 0 || info.MultiPV > 1 {
+			return
+		}
+		if !solved(info.PV[0], best, avoid) {
+			r.FoundDepth, r.FoundTime = 0, 0
+		} else if r.FoundDepth == 0 {
+			r.FoundDepth, r.FoundTime = info.Depth, info.Time
+		}
+	}
+	start := time.Now()
+	result := e.Search(p.Board, limits)
+	r.Time = time.Since(start)
+	if result.BestMove != nil {
+		r.Move = result.BestMove.String()
+	}
+	r.Score, r.Depth, r.Nodes = result.Score, result.Depth, result.Nodes
+	r.Solved = solved(result.BestMove, best, avoid)
+	if !r.Solved {
+		r.FoundDepth, r.FoundTime = 0, 0
+	}
+	return r
+}
+
+// Run 依次搜索所有局面，每个局面搜索结束后调用 onResult
+func Run(e *engine.Engine, positions []*Position, limits engine.Limits, onResult func(r *Result)) *Summary {
+	summary := &Summary{
+		DepthLimit: limits.Depth,
+		MoveTime:   limits.MoveTime,
+		NodeLimit:  limits.Nodes,
+		Positions:  len(positions),
+	}
+	for _, p := range positions {
+		r := Search(e, p, limits)
+		if r.Solved {
+			summary.Solved++
+		}
+		summary.Nodes += r.Nodes
+		summary.Time += r.Time
+		summary.Results = append(summary.Results, r)
+		if onResult != nil {
+			onResult(&summary.Results[len(summary.Results)-1])
+		}
+	}
+	return summary
+}
@@ -0,0 +1,463 @@
+package external
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+type Protocol int
+
+const (
+	// Auto 先尝试 UCCI，没有响应再尝试 UCI
+	Auto Protocol = iota
+	UCCI
+	UCI
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case UCCI:
+		return "ucci"
+	case UCI:
+		return "uci"
+	}
+	return "auto"
+}
+
+var (
+	ErrTimeout  = errors.New("external: engine did not respond in time")
+	ErrCrashed  = errors.New("external: engine process exited")
+	ErrProtocol = errors.New("external: engine supports neither ucci nor uci")
+)
+
+type Config struct {
+	Path     string
+	Args     []string
+	Protocol Protocol
+	// Options 在每次启动引擎后发送
+	Options map[string]string
+	// Timeout 是等待握手和 readyok 的时间，也是搜索超出时限或者调用 Stop 之后额外等待的时间
+	Timeout time.Duration
+	// SearchTimeout 是只限制深度或节点数的搜索最多等待的时间，为 0 时使用 DefaultSearchTimeout
+	SearchTimeout time.Duration
+	// MaxRestarts 是引擎崩溃后自动重启的最大次数
+	MaxRestarts int
+}
+
+// DefaultSearchTimeout 足够正常的引擎完成任何深度或节点数限制的搜索，超过时认为引擎已经失去响应
+const DefaultSearchTimeout = 10 * time.Minute
+
+type Engine struct {
+	// OnInfo 在收到引擎的 info 输出时调用
+	OnInfo func(info *engine.Info)
+
+	config   Config
+	protocol Protocol
+	name     string
+	restarts int
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+	// stopped 在无限搜索中调用 Stop 时关闭
+	stopped chan struct{}
+}
+
+func Start(config Config) (*Engine, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.SearchTimeout <= 0 {
+		config.SearchTimeout = DefaultSearchTimeout
+	}
+	e := &Engine{config: config}
+	if err := e.start(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) Name() string {
+	return e.name
+}
+
+func (e *Engine) Protocol() Protocol {
+	return e.protocol
+}
+
+func (e *Engine) start() error {
+	cmd := exec.Command(e.config.Path, e.config.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	lines := make(chan string, 256)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(lines)
+		cmd.Wait()
+	}()
+	e.mu.Lock()
+	e.cmd, e.stdin, e.lines = cmd, stdin, lines
+	e.mu.Unlock()
+
+	if err := e.handshake(); err != nil {
+		e.kill()
+		return err
+	}
+	for name, value := range e.config.Options {
+		e.setOption(name, value)
+	}
+	return e.isReady()
+}
+
+func (e *Engine) handshake() error {
+	protocols := []Protocol{e.config.Protocol}
+	if e.config.Protocol == Auto {
+		protocols = []Protocol{UCCI, UCI}
+	}
+	for _, p := range protocols {
+		e.send(p.String())
+		ok := p.String() + "ok"
+		err := e.readUntil(time.Now().Add(e.config.Timeout), func(line string) bool {
+			if strings.HasPrefix(line, "id name ") {
+				e.name = strings.TrimPrefix(line, "id name ")
+			}
+			return line == ok
+		})
+		if err == nil {
+			e.protocol = p
+			return nil
+		} else if err == ErrCrashed {
+			return err
+		}
+	}
+	return ErrProtocol
+}
+
+func (e *Engine) isReady() error {
+	e.send("isready")
+	return e.readUntil(time.Now().Add(e.config.Timeout), func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+func (e *Engine) send(format string, a ...interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stdin != nil {
+		fmt.Fprintf(e.stdin, format+"\n", a...)
+	}
+}
+
+// readUntil 读取引擎输出直到 done 返回 true
+func (e *Engine) readUntil(deadline time.Time, done func(line string) bool) error {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrCrashed
+			}
+			if done(line) {
+				return nil
+			}
+		case <-timer.C:
+			return ErrTimeout
+		}
+	}
+}
+
+// readUntilStopped 在无限搜索中读取引擎输出，调用 Stop 之后引擎要在 Timeout 内结束搜索
+func (e *Engine) readUntilStopped(stopped <-chan struct{}, done func(line string) bool) error {
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrCrashed
+			}
+			if done(line) {
+				return nil
+			}
+		case <-stopped:
+			return e.readUntil(time.Now().Add(e.config.Timeout), done)
+		}
+	}
+}
+
+func (e *Engine) kill() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cmd != nil && e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.stdin = nil
+}
+
+// recover 在引擎崩溃或失去响应后重新启动引擎
+func (e *Engine) recover(cause error) error {
+	e.kill()
+	if e.restarts >= e.config.MaxRestarts {
+		return cause
+	}
+	e.restarts++
+	if err := e.start(); err != nil {
+		return err
+	}
+	return cause
+}
+
+func (e *Engine) setOption(name string, value string) {
+	if e.protocol == UCI {
+		e.send("setoption name %s value %s", name, value)
+	} else {
+		e.send("setoption %s %s", name, value)
+	}
+}
+
+func (e *Engine) SetOption(name string, value string) error {
+	if e.config.Options == nil {
+		e.config.Options = map[string]string{}
+	}
+	e.config.Options[name] = value
+	e.setOption(name, value)
+	if err := e.isReady(); err != nil {
+		return e.recover(err)
+	}
+	return nil
+}
+
+func (e *Engine) NewGame() error {
+	if e.protocol == UCI {
+		e.send("ucinewgame")
+	}
+	if err := e.isReady(); err != nil {
+		return e.recover(err)
+	}
+	return nil
+}
+
+// PositionCommand 根据棋盘的初始局面和着法历史生成 position 命令
+func PositionCommand(b *chess.Board) string {
+	root := b.Copy()
+	for root.Pop() != nil {
+	}
+	command := "position fen " + root.Fen()
+	if moves := b.MoveStack(); len(moves) > 0 {
+		command += " moves"
+		for _, move := range moves {
+			command += " " + move.String()
+		}
+	}
+	return command
+}
+
+func (e *Engine) goCommand(limits engine.Limits) string {
+	command := "go"
+	if limits.Infinite {
+		if e.protocol == UCI {
+			return command + " infinite"
+		}
+		return command + " depth infinite"
+	}
+	if limits.Depth > 0 {
+		command += " depth " + strconv.Itoa(limits.Depth)
+	}
+	if limits.Nodes > 0 {
+		command += " nodes " + strconv.FormatUint(limits.Nodes, 10)
+	}
+	if limits.MoveTime > 0 {
+		ms := strconv.FormatInt(limits.MoveTime.Milliseconds(), 10)
+		if e.protocol == UCI {
+			command += " movetime " + ms
+		} else {
+			// UCCI 没有 movetime，用只剩一步的时限代替
+			command += " time " + ms + " movestogo 1"
+		}
+	}
+	return command
+}
+
+// Search 把局面发送给引擎并等待 bestmove，引擎崩溃或超时会返回错误并自动重启
+func (e *Engine) Search(b *chess.Board, limits engine.Limits) (engine.Result, error) {
+	result := engine.Result{}
+	var stopped chan struct{}
+	if limits.Infinite {
+		stopped = make(chan struct{})
+		e.mu.Lock()
+		e.stopped = stopped
+		e.mu.Unlock()
+	}
+	e.send(PositionCommand(b))
+	e.send(e.goCommand(limits))
+
+	// 每种搜索都有时限，超时后认为引擎失去响应，重启引擎
+	var deadline time.Time
+	switch {
+	case limits.MoveTime > 0:
+		deadline = time.Now().Add(limits.MoveTime + e.config.Timeout)
+	case limits.Depth > 0 || limits.Nodes > 0:
+		deadline = time.Now().Add(e.config.SearchTimeout)
+	default:
+		deadline = time.Now().Add(e.config.Timeout)
+	}
+	done := func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return false
+		}
+		switch fields[0] {
+		case "info":
+			if info := ParseInfo(fields[1:]); info != nil {
+				result.Depth = info.Depth
+				result.Score = info.Score
+				result.Nodes = info.Nodes
+				if e.OnInfo != nil {
+					e.OnInfo(info)
+				}
+			}
+		case "nobestmove":
+			return true
+		case "bestmove":
+			if len(fields) > 1 {
+				result.BestMove, _ = chess.ParseMove(fields[1])
+			}
+			if len(fields) > 3 && fields[2] == "ponder" {
+				result.PonderMove, _ = chess.ParseMove(fields[3])
+			}
+			return true
+		}
+		return false
+	}
+
+	var err error
+	if limits.Infinite {
+		err = e.readUntilStopped(stopped, done)
+		e.mu.Lock()
+		if e.stopped == stopped {
+			e.stopped = nil
+		}
+		e.mu.Unlock()
+	} else if err = e.readUntil(deadline, done); err == ErrTimeout {
+		// 超时之后先要求引擎停止，仍然没有响应再重启
+		e.send("stop")
+		err = e.readUntil(time.Now().Add(e.config.Timeout), done)
+	}
+	if err != nil {
+		return result, e.recover(err)
+	}
+	return result, nil
+}
+
+// Stop 要求引擎立即给出结果，Search 随后返回
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	if e.stopped != nil {
+		close(e.stopped)
+		e.stopped = nil
+	}
+	e.mu.Unlock()
+	e.send("stop")
+}
+
+func (e *Engine) Close() error {
+	e.send("quit")
+	select {
+	case <-e.drain():
+	case <-time.After(e.config.Timeout):
+		e.kill()
+	}
+	return nil
+}
+
+func (e *Engine) drain() <-chan struct{} {
+	done := make(chan struct{})
+	lines := e.lines
+	go func() {
+		for range lines {
+		}
+		close(done)
+	}()
+	return done
+}
+
+// ParseInfo 解析 info 命令的参数，同时支持 UCCI 和 UCI 的分数格式
+func ParseInfo(args []string) *engine.Info {
+	info := &engine.Info{}
+	found := false
+	for i := 0; i < len(args); i++ {
+		next := func() int {
+			if i+1 >= len(args) {
+				return 0
+			}
+			i++
+			n, _ := strconv.Atoi(args[i])
+			return n
+		}
+		switch args[i] {
+		case "depth":
+			info.Depth = next()
+			found = true
+		case "seldepth":
+			info.SelDepth = next()
+		case "multipv":
+			info.MultiPV = next()
+		case "nodes":
+			info.Nodes = uint64(next())
+		case "time":
+			info.Time = time.Duration(next()) * time.Millisecond
+		case "score":
+			if i+1 < len(args) && args[i+1] == "cp" {
+				i++
+				info.Score = next()
+			} else if i+1 < len(args) && args[i+1] == "mate" {
+				i++
+				mate := next()
+				if mate > 0 {
+					info.Score = engine.MateScore - 2*mate + 1
+				} else {
+					info.Score = -engine.MateScore - 2*mate
+				}
+			} else {
+				info.Score = next()
+			}
+		case "pv":
+			for _, text := range args[i+1:] {
+				move, err := chess.ParseMove(text)
+				if err != nil {
+					break
+				}
+				info.PV = append(info.PV, move)
+			}
+			i = len(args)
+		case "string":
+			i = len(args)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return info
+}
@@ -0,0 +1,135 @@
+package external
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+func buildFakeEngine(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "fakeengine")
+	cmd := exec.Command("go", "build", "-o", path, "./testdata/fakeengine")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build fake engine: %v\n%s", err, out)
+	}
+	return path
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	path := buildFakeEngine(t)
+	for _, proto := range []Protocol{UCCI, UCI} {
+		e, err := Start(Config{Path: path, Args: []string{"-proto", proto.String()}, Timeout: time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Protocol() != proto || e.Name() != "FakeEngine" {
+			t.Errorf("negotiated %v %q, want %v FakeEngine", e.Protocol(), e.Name(), proto)
+		}
+		e.Close()
+	}
+}
+
+func TestSearch(t *testing.T) {
+	path := buildFakeEngine(t)
+	e, err := Start(Config{Path: path, Args: []string{"-proto", "uci"}, Protocol: UCI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	var info *engine.Info
+	e.OnInfo = func(i *engine.Info) {
+		info = i
+	}
+	b := chess.NewBoard()
+	move, _ := chess.ParseMove("h2e2")
+	b.Push(move)
+	result, err := e.Search(b, engine.Limits{Depth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove == nil || !b.IsLegal(result.BestMove) {
+		t.Errorf("illegal best move %v", result.BestMove)
+	}
+	if info == nil || info.Mate() != 2 || len(info.PV) != 1 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestPositionCommand(t *testing.T) {
+	b := chess.NewBoard()
+	for _, text := range []string{"h2e2", "h9g7"} {
+		move, _ := chess.ParseMove(text)
+		b.Push(move)
+	}
+	expected := "position fen " + chess.StartingFen + " moves h2e2 h9g7"
+	if command := PositionCommand(b); command != expected {
+		t.Errorf("got %q, want %q", command, expected)
+	}
+}
+
+func TestCrashRecovery(t *testing.T) {
+	path := buildFakeEngine(t)
+	e, err := Start(Config{Path: path, Args: []string{"-crash"}, Timeout: time.Second, MaxRestarts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if _, err := e.Search(chess.NewBoard(), engine.Limits{Depth: 1}); err != ErrCrashed {
+		t.Errorf("got %v, want ErrCrashed", err)
+	}
+	// 重启后的引擎应该能继续响应
+	if err := e.NewGame(); err != nil {
+		t.Errorf("engine not restarted: %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	path := buildFakeEngine(t)
+	e, err := Start(Config{Path: path, Args: []string{"-hang"}, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if _, err := e.Search(chess.NewBoard(), engine.Limits{MoveTime: 50 * time.Millisecond}); err != ErrTimeout {
+		t.Errorf("got %v, want ErrTimeout", err)
+	}
+}
+
+// 只限制深度和无限搜索也有时限，引擎失去响应时不会一直等下去
+func TestSearchTimeout(t *testing.T) {
+	path := buildFakeEngine(t)
+	e, err := Start(Config{Path: path, Args: []string{"-hang"}, Timeout: 100 * time.Millisecond, SearchTimeout: 200 * time.Millisecond, MaxRestarts: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if _, err := e.Search(chess.NewBoard(), engine.Limits{Depth: 20}); err != ErrTimeout {
+		t.Errorf("depth search: got %v, want ErrTimeout", err)
+	}
+	// 超时后引擎已经重启，无限搜索在 Stop 之后才开始计时
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		e.Stop()
+	}()
+	start := time.Now()
+	if _, err := e.Search(chess.NewBoard(), engine.Limits{Infinite: true}); err != ErrTimeout {
+		t.Errorf("infinite search: got %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("infinite search timed out after %v, before Stop", elapsed)
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	info := ParseInfo([]string{"depth", "7", "seldepth", "12", "multipv", "2", "score", "cp", "-35", "nodes", "1000", "time", "20", "pv", "h2e2", "h9g7"})
+	if info.Depth != 7 || info.SelDepth != 12 || info.MultiPV != 2 || info.Score != -35 || info.Nodes != 1000 || len(info.PV) != 2 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if info := ParseInfo([]string{"string", "hello"}); info != nil {
+		t.Errorf("expected nil for info string, got %+v", info)
+	}
+}
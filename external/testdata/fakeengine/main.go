@@ -0,0 +1,73 @@
+// fakeengine 是测试用的引擎，总是走第一个合法着法。
+//
+// 参数：-proto ucci|uci 只响应对应的协议，-crash 在收到 go 后退出，-hang 收到 go 后不再响应
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+)
+
+func main() {
+	proto := flag.String("proto", "ucci", "protocol to answer")
+	crash := flag.Bool("crash", false, "exit on go")
+	hang := flag.Bool("hang", false, "never answer go")
+	flag.Parse()
+
+	board := chess.NewBoard()
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "ucci", "uci":
+			if fields[0] != *proto {
+				fmt.Println("unknown command", fields[0])
+				continue
+			}
+			fmt.Println("id name FakeEngine")
+			fmt.Println(*proto + "ok")
+		case "isready":
+			fmt.Println("readyok")
+		case "position":
+			i := 0
+			for i < len(fields) && fields[i] != "moves" {
+				i++
+			}
+			board, _ = chess.NewBoardFromFen(strings.Join(fields[2:i], " "))
+			if i < len(fields) {
+				for _, text := range fields[i+1:] {
+					move, _ := chess.ParseMove(text)
+					board.Push(move)
+				}
+			}
+		case "go":
+			if *crash {
+				os.Exit(1)
+			}
+			if *hang {
+				continue
+			}
+			moves := board.LegalMoves()
+			if len(moves) == 0 {
+				fmt.Println("nobestmove")
+				continue
+			}
+			if *proto == "uci" {
+				fmt.Printf("info depth 1 score mate 2 nodes 10 pv %s\n", moves[0])
+			} else {
+				fmt.Printf("info depth 1 score 35 nodes 10 pv %s\n", moves[0])
+			}
+			fmt.Printf("bestmove %s\n", moves[0])
+		case "quit":
+			return
+		}
+	}
+}
@@ -1,26 +1,123 @@
 package main
 
 import (
+	"fmt"
 	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/game"
 	"github.com/clysto/gochess/resources"
+	"github.com/clysto/gochess/tablebase"
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
 	"image/color"
 )
 
+// TablebaseDir 是 GUI 查询残局库的目录，目录不存在时不显示残局库结果
+const TablebaseDir = "tablebases"
+
+const (
+	BoardWidth      = 1520
+	BoardHeight     = 1680
+	BottomBarHeight = 560
+)
+
 type Game struct {
 	fromSquare uint8
-	board      *chess.Board
+	// record 是当前对局的棋谱，board 是棋谱中当前位置的局面
+	record    *game.Cursor
+	board     *chess.Board
+	tablebase *tablebase.Tablebase
+	// result 不是 NoResult 时对局已经结束，不再接受走子
+	result chess.Result
+	// saved 是启动时读到的上次没有下完的对局，在新的一局中走子之后就不能再继续它了
+	saved *game.Game
+
+	// settings 是底栏中正在编辑的设置，round 是当前对局使用的设置
+	settings Settings
+	round    Settings
+	buttons  []*button
+	engine   *engine.Engine
+	// thinking 不为 nil 时电脑正在搜索
+	thinking <-chan engine.Result
+	// paused 为 true 时电脑暂停走棋，在棋谱中前进后退之后直到玩家走出一步，
+	// 否则后退到电脑走棋的局面时电脑会立即重新走一步
+	paused   bool
+	analyzer *analyzer
 }
 
 func NewGame() *Game {
-	return &Game{
-		board: chess.NewBoard(),
+	tb, _ := tablebase.Open(TablebaseDir)
+	g := &Game{
+		tablebase: tb,
+		settings:  DefaultSettings,
+		engine:    engine.NewEngine(),
+		analyzer:  newAnalyzer(),
+	}
+	if tb != nil {
+		g.engine.SetTablebase(tb)
+	}
+	g.saved = loadAutosave()
+	g.NewRound()
+	g.layoutButtons()
+	return g
+}
 
+// ResultText 返回对局结束时显示的文字
+func (g *Game) ResultText() string {
+	switch g.result {
+	case chess.RedWins:
+		return "红方胜"
+	case chess.BlackWins:
+		return "黑方胜"
+	case chess.Draw:
+		return "和棋"
+	}
+	return ""
+}
+
+// TablebaseText 返回当前局面在残局库中的结果，局面不在残局库中时返回空字符串
+func (g *Game) TablebaseText() string {
+	if g.tablebase == nil {
+		return ""
+	}
+	wdl, dtm, ok := g.tablebase.Probe(g.board)
+	if !ok {
+		return ""
+	}
+	if wdl == tablebase.Draw {
+		return "残局库：和棋"
+	}
+	winner := g.board.Turn()
+	if wdl == tablebase.Loss {
+		winner = !winner
+	}
+	name := "红方"
+	if winner == chess.Black {
+		name = "黑方"
 	}
+	return fmt.Sprintf("残局库：%s %d 步胜", name, (dtm+1)/2)
 }
 
 func (g *Game) Update() error {
-	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x, y := ebiten.CursorPosition()
+		for _, b := range g.buttons {
+			if b.contains(x, y) {
+				b.onClick()
+				g.layoutButtons()
+				return nil
+			}
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) {
+		g.navigate(g.record.Back)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) {
+		g.navigate(g.record.Forward)
+	}
+	g.updateEngine()
+	g.analyzer.update(g.board, g.result != chess.NoResult)
+	if g.result == chess.NoResult && !g.engineTurn() && ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
 		x, y := ebiten.CursorPosition()
 		sq := g.GetClickSquare(x, y)
 		piece := g.board.PieceAt(sq)
@@ -28,15 +125,38 @@ func (g *Game) Update() error {
 			g.fromSquare = sq
 		} else if (piece == nil || piece.Color != g.board.Turn()) && g.fromSquare > 0 {
 			move := &chess.Move{FromSquare: g.fromSquare, ToSquare: sq}
-			if g.board.IsPseudoLegal(move) {
-				g.board.Push(move)
-				g.fromSquare = 0
+			if g.board.IsLegal(move) {
+				g.play(move)
 			}
 		}
 	}
 	return nil
 }
 
+// play 在棋谱的当前位置走一步并自动保存。从之前的局面走出不同的着法时，新的着法成为主线，
+// 原来的着法保留为变着
+func (g *Game) play(move *chess.Move) {
+	g.record.PlayMainLine(move)
+	g.fromSquare = 0
+	g.paused = false
+	g.result = g.record.Game().Result
+	g.autosave()
+	if g.saved != nil {
+		g.saved = nil
+		g.layoutButtons()
+	}
+}
+
+// navigate 在棋谱中前进或者后退一步，正在进行的搜索会被停止，电脑暂停走棋直到玩家走子
+func (g *Game) navigate(step func() bool) {
+	g.stopThinking()
+	if step() {
+		g.fromSquare = 0
+		g.result = g.board.Result()
+		g.paused = g.round.VsEngine
+	}
+}
+
 func (g *Game) GetClickSquare(x, y int) uint8 {
 	file := (x-40)/160 + 3
 	rank := (y-40)/160 + 3
@@ -75,9 +195,20 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 
 	screen.DrawImage(boardImage, nil)
-	bottomBarImage := ebiten.NewImage(1520, 150)
+	bottomBarImage := ebiten.NewImage(BoardWidth, BottomBarHeight)
 	bottomBarImage.Fill(color.White)
-	g.DrawImageAt(screen, bottomBarImage, 0, 1680)
+	if s := g.ResultText(); s != "" {
+		text.Draw(bottomBarImage, s, resources.MaShanZhengRegularFont, 40, 250, color.Black)
+	} else if s := g.TablebaseText(); s != "" {
+		text.Draw(bottomBarImage, s, resources.MaShanZhengRegularFont, 40, 250, color.Black)
+	}
+	for i, line := range g.analyzer.Lines() {
+		text.Draw(bottomBarImage, line, resources.MaShanZhengRegularFont, 40, 340+i*80, color.Black)
+	}
+	g.DrawImageAt(screen, bottomBarImage, 0, BoardHeight)
+	for _, b := range g.buttons {
+		g.DrawImageAt(screen, b.image, float64(b.x), float64(b.y))
+	}
 }
 
 func (g *Game) DrawImageAt(screen *ebiten.Image, image *ebiten.Image, x float64, y float64) {
@@ -93,5 +224,5 @@ func (g *Game) DrawPieceAt(screen *ebiten.Image, image *ebiten.Image, sq uint8)
 }
 
 func (g *Game) Layout(_, _ int) (screenWidth, screenHeight int) {
-	return 1520, 1680 + 150
+	return BoardWidth, BoardHeight + BottomBarHeight
 }
@@ -0,0 +1,101 @@
+package game
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+// Cursor 是在着法树中浏览的位置，同时维护这个位置的局面
+type Cursor struct {
+	game  *Game
+	node  *Node
+	board *chess.Board
+}
+
+// NewCursor 返回指向棋谱开头的 Cursor
+func NewCursor(g *Game) (*Cursor, error) {
+	b, err := g.Board()
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{game: g, node: g.Root, board: b}, nil
+}
+
+func (c *Cursor) Game() *Game {
+	return c.game
+}
+
+func (c *Cursor) Node() *Node {
+	return c.node
+}
+
+// Board 返回当前的局面，调用者不能修改它
+func (c *Cursor) Board() *chess.Board {
+	return c.board
+}
+
+// Forward 沿着主线前进一步，已经到达末尾时返回 false
+func (c *Cursor) Forward() bool {
+	return c.Variation(0)
+}
+
+// Variation 进入当前位置之后的第 i 个分支，0 是主线
+func (c *Cursor) Variation(i int) bool {
+	if i < 0 || i >= len(c.node.Children) {
+		return false
+	}
+	c.node = c.node.Children[i]
+	c.board.Push(c.node.Move)
+	return true
+}
+
+// Back 后退一步，已经在开头时返回 false
+func (c *Cursor) Back() bool {
+	if c.node.Parent == nil {
+		return false
+	}
+	c.board.Pop()
+	c.node = c.node.Parent
+	return true
+}
+
+// ToStart 回到棋谱的开头
+func (c *Cursor) ToStart() {
+	for c.Back() {
+	}
+}
+
+// ToEnd 沿着当前分支的主线前进到末尾
+func (c *Cursor) ToEnd() {
+	for c.Forward() {
+	}
+}
+
+// Goto 跳到着法树中的 node，node 必须属于同一个棋谱
+func (c *Cursor) Goto(node *Node) {
+	c.ToStart()
+	for _, move := range node.Moves() {
+		c.board.Push(move)
+	}
+	c.node = node
+}
+
+// Play 在当前位置走一步，着法树中已经有这一步时沿着它前进，否则添加一个新的分支。
+// 着法必须是合法的
+func (c *Cursor) Play(move *chess.Move) *Node {
+	node := c.node.Find(move)
+	if node == nil {
+		node = c.node.Add(move)
+	}
+	c.node = node
+	c.board.Push(move)
+	return node
+}
+
+// PlayMainLine 走一步并把它提升为主线，棋谱的结果总是按照新的局面重新判断，
+// 悔棋之后另走一步时原来的结果会被清除
+func (c *Cursor) PlayMainLine(move *chess.Move) *Node {
+	node := c.Play(move)
+	node.PromoteToMainLine()
+	c.game.Result = c.board.Result()
+	return node
+}
@@ -0,0 +1,171 @@
+// Package game 是棋谱的着法树，包括标签、变着、注释和数字注释符号，
+// 各种棋谱格式的读写和 GUI 都使用这个结构。
+package game
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Node 是着法树的一个节点，根节点没有着法
+type Node struct {
+	Move *chess.Move
+	// Comment 是这一步之后的注释，根节点的注释在第一步之前
+	Comment string
+	// NAGs 是数字注释符号，例如 1 表示好棋 (!)，2 表示坏棋 (?)
+	NAGs   []int
+	Parent *Node
+	// Children 的第一个是主线，其余的是变着
+	Children []*Node
+}
+
+// Add 在节点之后添加一步，已经有后续着法时新的一步成为变着
+func (n *Node) Add(move *chess.Move) *Node {
+	child := &Node{Move: move, Parent: n}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+// Find 返回着法为 move 的后续节点，没有时返回 nil
+func (n *Node) Find(move *chess.Move) *Node {
+	for _, child := range n.Children {
+		if *child.Move == *move {
+			return child
+		}
+	}
+	return nil
+}
+
+// Ply 返回节点在着法树中的深度，根节点为 0
+func (n *Node) Ply() int {
+	ply := 0
+	for node := n; node.Parent != nil; node = node.Parent {
+		ply++
+	}
+	return ply
+}
+
+// Moves 返回从根节点到这个节点的着法
+func (n *Node) Moves() []*chess.Move {
+	moves := make([]*chess.Move, n.Ply())
+	i := len(moves) - 1
+	for node := n; node.Parent != nil; node = node.Parent {
+		moves[i] = node.Move
+		i--
+	}
+	return moves
+}
+
+func (n *Node) index() int {
+	for i, child := range n.Parent.Children {
+		if child == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsMainLine 判断节点是否在整局棋的主线上
+func (n *Node) IsMainLine() bool {
+	for node := n; node.Parent != nil; node = node.Parent {
+		if node.index() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Promote 把节点所在的变着和前一个变着交换位置，第一个变着和主线交换
+func (n *Node) Promote() {
+	if n.Parent == nil {
+		return
+	}
+	children := n.Parent.Children
+	if i := n.index(); i > 0 {
+		children[i-1], children[i] = children[i], children[i-1]
+	}
+}
+
+// PromoteToMainLine 把节点所在的变着一直提升为整局棋的主线
+func (n *Node) PromoteToMainLine() {
+	for node := n; node.Parent != nil; node = node.Parent {
+		children := node.Parent.Children
+		if i := node.index(); i > 0 {
+			copy(children[1:i+1], children[:i])
+			children[0] = node
+		}
+	}
+}
+
+// Remove 从着法树中删除这个节点和它之后的所有着法
+func (n *Node) Remove() {
+	if n.Parent == nil {
+		return
+	}
+	children := n.Parent.Children
+	i := n.index()
+	n.Parent.Children = append(children[:i:i], children[i+1:]...)
+	n.Parent = nil
+}
+
+// Game 是一局棋谱，着法树从 FEN 标签给出的局面开始，没有 FEN 标签时从初始局面开始
+type Game struct {
+	Tags   []Tag
+	Root   *Node
+	Result chess.Result
+}
+
+func NewGame() *Game {
+	return &Game{Root: &Node{}}
+}
+
+// NewGameFromBoard 返回从 b 的局面开始的空棋谱
+func NewGameFromBoard(b *chess.Board) *Game {
+	g := NewGame()
+	if fen := b.Fen(); fen != chess.NewBoard().Fen() {
+		g.SetTag("FEN", fen)
+	}
+	return g
+}
+
+func (g *Game) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// SetTag 设置标签，已有同名标签时替换它的值
+func (g *Game) SetTag(name string, value string) {
+	for i := range g.Tags {
+		if g.Tags[i].Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// Board 返回棋谱的起始局面
+func (g *Game) Board() (*chess.Board, error) {
+	if fen := g.Tag("FEN"); fen != "" {
+		return chess.NewBoardFromFen(fen)
+	}
+	return chess.NewBoard(), nil
+}
+
+// MainLine 返回主线的着法
+func (g *Game) MainLine() []*chess.Move {
+	var moves []*chess.Move
+	for node := g.Root; len(node.Children) > 0; {
+		node = node.Children[0]
+		moves = append(moves, node.Move)
+	}
+	return moves
+}
@@ -0,0 +1,134 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+)
+
+func mustParse(t *testing.T, moves ...string) []*chess.Move {
+	t.Helper()
+	var result []*chess.Move
+	for _, s := range moves {
+		move, err := chess.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, move)
+	}
+	return result
+}
+
+// 1. h2e2 h9g7 (1... b7e7) (1... b9c7) 2. h0g2
+func sample(t *testing.T) (*Game, []*Node) {
+	g := NewGame()
+	moves := mustParse(t, "h2e2", "h9g7", "b7e7", "b9c7", "h0g2")
+	first := g.Root.Add(moves[0])
+	main := first.Add(moves[1])
+	nodes := []*Node{first, main, first.Add(moves[2]), first.Add(moves[3]), main.Add(moves[4])}
+	return g, nodes
+}
+
+func TestPromote(t *testing.T) {
+	g, nodes := sample(t)
+	nodes[3].Promote()
+	if !reflect.DeepEqual(nodes[0].Children, []*Node{nodes[1], nodes[3], nodes[2]}) {
+		t.Fatal("promote did not swap with the previous variation")
+	}
+	nodes[2].PromoteToMainLine()
+	if !reflect.DeepEqual(nodes[0].Children, []*Node{nodes[2], nodes[1], nodes[3]}) {
+		t.Fatal("promote to main line did not keep the order of the other variations")
+	}
+	if !nodes[2].IsMainLine() || nodes[4].IsMainLine() {
+		t.Error("wrong main line")
+	}
+	if !reflect.DeepEqual(g.MainLine(), mustParse(t, "h2e2", "b7e7")) {
+		t.Errorf("main line %v", g.MainLine())
+	}
+	nodes[1].Remove()
+	if len(nodes[0].Children) != 2 || nodes[0].Find(nodes[1].Move) != nil {
+		t.Error("variation was not removed")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	g, nodes := sample(t)
+	c, err := NewCursor(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Back() {
+		t.Error("moved back from the start")
+	}
+	c.ToEnd()
+	if c.Node() != nodes[4] || c.Board().Ply() != 3 {
+		t.Fatalf("end at ply %d", c.Board().Ply())
+	}
+	c.Back()
+	c.Back()
+	if !c.Variation(2) || c.Node() != nodes[3] || c.Variation(0) {
+		t.Fatal("failed to enter variation")
+	}
+	want := chess.NewBoard()
+	for _, move := range nodes[3].Moves() {
+		want.Push(move)
+	}
+	if c.Board().Fen() != want.Fen() {
+		t.Errorf("board %s, want %s", c.Board().Fen(), want.Fen())
+	}
+
+	c.Goto(nodes[1])
+	if node := c.Play(nodes[4].Move); node != nodes[4] {
+		t.Error("playing an existing move added a new node")
+	}
+	c.Back()
+	move := mustParse(t, "b0c2")[0]
+	if node := c.Play(move); node.Parent != nodes[1] || len(nodes[1].Children) != 2 {
+		t.Error("new move was not added as a variation")
+	}
+	c.ToStart()
+	if c.Node() != g.Root || c.Board().Fen() != chess.NewBoard().Fen() {
+		t.Error("failed to go back to the start")
+	}
+}
+
+// 将死之后悔棋另走一步，棋谱应该回到没有下完的状态，才能继续下
+func TestPlayMainLine(t *testing.T) {
+	b, err := chess.NewBoardFromFen("3k5/R8/9/9/9/9/9/9/9/1R3K3 w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGameFromBoard(b)
+	c, err := NewCursor(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := mustParse(t, "b0b9", "b0b8")
+	c.PlayMainLine(moves[0])
+	if g.Result != chess.RedWins {
+		t.Fatalf("result %s after mate", g.Result)
+	}
+	c.Back()
+	c.PlayMainLine(moves[1])
+	if g.Result != chess.NoResult {
+		t.Errorf("result %s after taking back the mate", g.Result)
+	}
+	if !reflect.DeepEqual(g.MainLine(), moves[1:]) {
+		t.Errorf("main line %v", g.MainLine())
+	}
+}
+
+func TestNAG(t *testing.T) {
+	for nag, symbol := range map[int]string{NAGGood: "!", NAGDubious: "?!", NAGRedDecisive: "+-", 146: "$146"} {
+		if got := NAGSymbol(nag); got != symbol {
+			t.Errorf("NAGSymbol(%d) = %q, want %q", nag, got, symbol)
+		}
+	}
+	if nag, ok := ParseNAG("!?"); !ok || nag != NAGSpeculative {
+		t.Errorf("ParseNAG(!?) = %d", nag)
+	}
+	if _, ok := ParseNAG("!!!"); ok {
+		t.Error("ParseNAG accepted an unknown symbol")
+	}
+}
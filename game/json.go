@@ -0,0 +1,106 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// jsonGame 是棋谱的 JSON 格式，着法树按 PGN 的方式展开：moves 是主线，
+// 每一步的 variations 是可以代替这一步的其他着法，每个变着又是一串着法
+type jsonGame struct {
+	Tags    []jsonTag    `json:"tags"`
+	Result  chess.Result `json:"result"`
+	Comment string       `json:"comment,omitempty"`
+	NAGs    []int        `json:"nags,omitempty"`
+	Moves   []jsonMove   `json:"moves"`
+}
+
+type jsonTag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type jsonMove struct {
+	Move       chess.Move   `json:"move"`
+	Comment    string       `json:"comment,omitempty"`
+	NAGs       []int        `json:"nags,omitempty"`
+	Variations [][]jsonMove `json:"variations,omitempty"`
+}
+
+// encodeLine 返回从 node 的第 i 个后续着法开始的一串着法
+func encodeLine(node *Node, i int) []jsonMove {
+	line := []jsonMove{}
+	for len(node.Children) > i {
+		child := node.Children[i]
+		m := jsonMove{Move: *child.Move, Comment: child.Comment, NAGs: child.NAGs}
+		if i == 0 {
+			for j := range node.Children[1:] {
+				m.Variations = append(m.Variations, encodeLine(node, j+1))
+			}
+		}
+		line = append(line, m)
+		node, i = child, 0
+	}
+	return line
+}
+
+func (g *Game) MarshalJSON() ([]byte, error) {
+	root := g.Root
+	if root == nil {
+		root = &Node{}
+	}
+	jg := jsonGame{Tags: []jsonTag{}, Result: g.Result, Comment: root.Comment, NAGs: root.NAGs}
+	for _, tag := range g.Tags {
+		jg.Tags = append(jg.Tags, jsonTag(tag))
+	}
+	jg.Moves = encodeLine(root, 0)
+	return json.Marshal(jg)
+}
+
+// decodeLine 把一串着法添加到 node 之后，b 是 node 的局面，会被修改
+func decodeLine(b *chess.Board, node *Node, line []jsonMove) error {
+	for _, m := range line {
+		move := m.Move
+		if !b.IsLegal(&move) {
+			return fmt.Errorf("game: illegal move %s at %s", move, b.Fen())
+		}
+		child := node.Add(&move)
+		child.Comment, child.NAGs = m.Comment, m.NAGs
+		for _, variation := range m.Variations {
+			if len(variation) == 0 {
+				continue
+			}
+			if err := decodeLine(b.Copy(), node, variation); err != nil {
+				return err
+			}
+		}
+		b.Push(&move)
+		node = child
+	}
+	return nil
+}
+
+// UnmarshalJSON 读取 JSON 格式的棋谱，并且在棋盘上重放检查每一步是否合法
+func (g *Game) UnmarshalJSON(data []byte) error {
+	var jg jsonGame
+	if err := json.Unmarshal(data, &jg); err != nil {
+		return err
+	}
+	decoded := NewGame()
+	for _, tag := range jg.Tags {
+		decoded.Tags = append(decoded.Tags, Tag(tag))
+	}
+	decoded.Result = jg.Result
+	decoded.Root.Comment, decoded.Root.NAGs = jg.Comment, jg.NAGs
+	b, err := decoded.Board()
+	if err != nil {
+		return err
+	}
+	if err := decodeLine(b, decoded.Root, jg.Moves); err != nil {
+		return err
+	}
+	*g = *decoded
+	return nil
+}
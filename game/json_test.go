@@ -0,0 +1,94 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// 棋谱的 JSON 格式：
+//
+//	tags        标签，按原来的顺序排列
+//	result      结果记号，1-0、0-1、1/2-1/2 或者 *
+//	comment     第一步之前的注释，可以省略
+//	nags        第一步之前的数字注释符号，可以省略
+//	moves       主线的着法，每一步是
+//	  move        ICCS 坐标
+//	  comment     这一步之后的注释，可以省略
+//	  nags        数字注释符号，可以省略
+//	  variations  可以代替这一步的变着，每个变着是一串同样格式的着法，可以省略
+const sampleJSON = `{
+  "tags": [
+    {
+      "name": "Event",
+      "value": "测试"
+    },
+    {
+      "name": "FEN",
+      "value": "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1"
+    }
+  ],
+  "result": "1-0",
+  "comment": "中炮",
+  "moves": [
+    {
+      "move": "h9g7",
+      "nags": [
+        1
+      ],
+      "variations": [
+        [
+          {
+            "move": "b7e7",
+            "comment": "顺炮"
+          },
+          {
+            "move": "h0g2"
+          }
+        ]
+      ]
+    },
+    {
+      "move": "h0g2",
+      "comment": "正常"
+    }
+  ]
+}`
+
+func TestJSON(t *testing.T) {
+	var g Game
+	if err := json.Unmarshal([]byte(sampleJSON), &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.Tag("Event") != "测试" || g.Result.String() != "1-0" || g.Root.Comment != "中炮" {
+		t.Errorf("unexpected game %+v", g)
+	}
+	first := g.Root.Children
+	if len(first) != 2 || first[1].Comment != "顺炮" || !reflect.DeepEqual(first[0].NAGs, []int{1}) {
+		t.Fatalf("unexpected tree")
+	}
+	if !reflect.DeepEqual(g.MainLine(), mustParse(t, "h9g7", "h0g2")) {
+		t.Errorf("main line %v", g.MainLine())
+	}
+	data, err := json.MarshalIndent(&g, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != sampleJSON {
+		t.Errorf("got\n%s\nwant\n%s", data, sampleJSON)
+	}
+
+	data, err = json.Marshal(NewGame())
+	if err != nil || string(data) != `{"tags":[],"result":"*","moves":[]}` {
+		t.Errorf("empty game %s %v", data, err)
+	}
+	for _, invalid := range []string{
+		`{"moves":[{"move":"a0a5"}]}`,
+		`{"moves":[{"move":"h2"}]}`,
+		`{"result":"2-0","moves":[]}`,
+	} {
+		if err := json.Unmarshal([]byte(invalid), &g); err == nil {
+			t.Errorf("expected error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package game
+
+import "strconv"
+
+// 常用的数字注释符号
+const (
+	NAGGood          = 1
+	NAGMistake       = 2
+	NAGBrilliant     = 3
+	NAGBlunder       = 4
+	NAGSpeculative   = 5
+	NAGDubious       = 6
+	NAGForced        = 7
+	NAGEqual         = 10
+	NAGUnclear       = 13
+	NAGRedSlight     = 14
+	NAGBlackSlight   = 15
+	NAGRedModerate   = 16
+	NAGBlackModerate = 17
+	NAGRedDecisive   = 18
+	NAGBlackDecisive = 19
+)
+
+var nagSymbols = map[int]string{
+	NAGGood:          "!",
+	NAGMistake:       "?",
+	NAGBrilliant:     "!!",
+	NAGBlunder:       "??",
+	NAGSpeculative:   "!?",
+	NAGDubious:       "?!",
+	NAGForced:        "□",
+	NAGEqual:         "=",
+	NAGUnclear:       "∞",
+	NAGRedSlight:     "⩲",
+	NAGBlackSlight:   "⩱",
+	NAGRedModerate:   "±",
+	NAGBlackModerate: "∓",
+	NAGRedDecisive:   "+-",
+	NAGBlackDecisive: "-+",
+}
+
+// NAGSymbol 返回数字注释符号对应的符号，没有对应符号时返回 $ 加数字
+func NAGSymbol(nag int) string {
+	if symbol, ok := nagSymbols[nag]; ok {
+		return symbol
+	}
+	return "$" + strconv.Itoa(nag)
+}
+
+// ParseNAG 把 ! ? 等符号转换为数字注释符号
+func ParseNAG(symbol string) (int, bool) {
+	for nag, s := range nagSymbols {
+		if s == symbol {
+			return nag, true
+		}
+	}
+	return 0, false
+}
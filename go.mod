@@ -7,6 +7,7 @@ require (
 	github.com/hajimehoshi/ebiten/v2 v2.2.4
 	github.com/holiman/uint256 v1.2.0
 	golang.org/x/image v0.0.0-20210628002857-a66eb6448b8d
+	golang.org/x/text v0.3.6
 )
 
 require (
@@ -17,5 +18,4 @@ require (
 	golang.org/x/mobile v0.0.0-20210902104108-5d9a33257ab5 // indirect
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
 	golang.org/x/sys v0.0.0-20210917161153-d61c044b1678 // indirect
-	golang.org/x/text v0.3.6 // indirect
 )
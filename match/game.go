@@ -0,0 +1,227 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/game"
+)
+
+// TimeControl 是每方的用时规则：Base 和 Increment 组成加秒制的棋钟，
+// MoveTime、Nodes 和 Depth 不为 0 时限制每一步的搜索
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+	MoveTime  time.Duration
+	Nodes     uint64
+	Depth     int
+}
+
+// ParseTimeControl 解析 "基本时间+加秒" 格式的用时，单位是秒，比如 "10+0.1"
+func ParseTimeControl(s string) (TimeControl, error) {
+	tc := TimeControl{}
+	parts := strings.SplitN(s, "+", 2)
+	base, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || base < 0 {
+		return tc, fmt.Errorf("match: invalid time control %q", s)
+	}
+	tc.Base = time.Duration(base * float64(time.Second))
+	if len(parts) == 2 {
+		increment, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || increment < 0 {
+			return tc, fmt.Errorf("match: invalid time control %q", s)
+		}
+		tc.Increment = time.Duration(increment * float64(time.Second))
+	}
+	return tc, nil
+}
+
+func (tc TimeControl) String() string {
+	if tc.Base > 0 {
+		return fmt.Sprintf("%g+%g", tc.Base.Seconds(), tc.Increment.Seconds())
+	}
+	return "-"
+}
+
+// limits 返回剩余时间为 clock 时这一步的搜索限制
+func (tc TimeControl) limits(clock time.Duration) engine.Limits {
+	limits := engine.Limits{MoveTime: tc.MoveTime, Nodes: tc.Nodes, Depth: tc.Depth}
+	if tc.Base > 0 {
+		t := engine.TimeForMove(clock, tc.Increment, 0)
+		if limits.MoveTime == 0 || t < limits.MoveTime {
+			limits.MoveTime = t
+		}
+	}
+	return limits
+}
+
+// Adjudication 是提前结束对局的规则，为 0 的项不启用
+type Adjudication struct {
+	// 双方连续 ResignMoves 步都认为一方落后至少 ResignScore 时判这一方负
+	ResignScore int
+	ResignMoves int
+	// 第 DrawPly 步之后双方连续 DrawMoves 步的分数绝对值都不超过 DrawScore 时判和
+	DrawScore int
+	DrawMoves int
+	DrawPly   int
+	// 对局超过 MaxPly 步时判和
+	MaxPly int
+}
+
+// 60 回合没有吃子判和
+const naturalLimit = 120
+
+// GameResult 是一局比赛的结果，Game 可以直接写出为 PGN
+type GameResult struct {
+	Game   *game.Game
+	Result chess.Result
+	// Reason 说明对局结束的原因
+	Reason string
+	// Red 是执红一方在 Config.Players 中的序号
+	Red int
+}
+
+// adjudicator 记录双方连续认输和求和的步数
+type adjudicator struct {
+	Adjudication
+	losing  [2]int
+	winning [2]int
+	drawn   int
+}
+
+func colorIndex(color bool) int {
+	if color == chess.Red {
+		return 0
+	}
+	return 1
+}
+
+// update 记录 color 一方走出本局第 ply 步时给出的分数，需要提前结束时返回结果
+func (a *adjudicator) update(ply int, color bool, score int) (chess.Result, string) {
+	c := colorIndex(color)
+	if a.ResignMoves > 0 {
+		switch {
+		case score <= -a.ResignScore:
+			a.losing[c]++
+			a.winning[c] = 0
+		case score >= a.ResignScore:
+			a.winning[c]++
+			a.losing[c] = 0
+		default:
+			a.losing[c], a.winning[c] = 0, 0
+		}
+		for _, loser := range []bool{chess.Red, chess.Black} {
+			l, w := colorIndex(loser), colorIndex(!loser)
+			if a.losing[l] >= a.ResignMoves && a.winning[w] >= a.ResignMoves {
+				if loser == chess.Red {
+					return chess.BlackWins, "adjudication: resign"
+				}
+				return chess.RedWins, "adjudication: resign"
+			}
+		}
+	}
+	if a.DrawMoves > 0 && ply >= a.DrawPly {
+		if score >= -a.DrawScore && score <= a.DrawScore {
+			a.drawn++
+		} else {
+			a.drawn = 0
+		}
+		if a.drawn >= 2*a.DrawMoves {
+			return chess.Draw, "adjudication: draw"
+		}
+	}
+	if a.MaxPly > 0 && ply >= a.MaxPly {
+		return chess.Draw, "adjudication: maximum length"
+	}
+	return chess.NoResult, ""
+}
+
+func loss(color bool) chess.Result {
+	if color == chess.Red {
+		return chess.BlackWins
+	}
+	return chess.RedWins
+}
+
+// PlayGame 让 red 和 black 从 fen 局面开始下一局棋。引擎出错、超时或者走出不合法的着法都判负，
+// 只有局面或者 NewGame 出错时才返回错误
+func PlayGame(red Player, black Player, fen string, tc TimeControl, adjudication Adjudication) (*GameResult, error) {
+	b, err := chess.NewBoardFromFen(fen)
+	if err != nil {
+		return nil, err
+	}
+	players := [2]Player{red, black}
+	for _, p := range players {
+		if err := p.NewGame(); err != nil {
+			return nil, err
+		}
+	}
+	g := game.NewGame()
+	node := g.Root
+	g.SetTag("Red", red.Name())
+	g.SetTag("Black", black.Name())
+	if b.Fen() != chess.StartingFen {
+		g.SetTag("FEN", b.Fen())
+	}
+	if tc.Base > 0 {
+		g.SetTag("TimeControl", tc.String())
+	}
+	finish := func(result chess.Result, reason string) (*GameResult, error) {
+		g.Result = result
+		g.SetTag("Termination", reason)
+		return &GameResult{Game: g, Result: result, Reason: reason}, nil
+	}
+
+	clocks := [2]time.Duration{tc.Base, tc.Base}
+	a := adjudicator{Adjudication: adjudication}
+	for ply := 1; ; ply++ {
+		if result := b.Result(); result != chess.NoResult {
+			switch {
+			case result == chess.Draw && b.IsInsufficientMaterial():
+				return finish(result, "insufficient material")
+			case result == chess.Draw:
+				return finish(result, "dead draw")
+			case b.IsCheck():
+				return finish(result, "checkmate")
+			}
+			return finish(result, "stalemate")
+		}
+		// 三次重复时一方长将判负。长捉等其他禁着规则比较复杂，不判和也不判负，
+		// 继续下直到判和规则、最大步数或者 60 回合限制结束对局
+		if b.RepetitionCount() >= 3 {
+			if checker, ok := b.PerpetualChecker(); ok {
+				return finish(loss(checker), "perpetual check")
+			}
+		}
+		if b.HalfmoveClock() >= naturalLimit {
+			return finish(chess.Draw, "60-move rule")
+		}
+
+		color := b.Turn()
+		c := colorIndex(color)
+		start := time.Now()
+		result, err := players[c].Search(b, tc.limits(clocks[c]))
+		elapsed := time.Since(start)
+		if err != nil {
+			return finish(loss(color), fmt.Sprintf("%s: %v", players[c].Name(), err))
+		}
+		if tc.Base > 0 {
+			if clocks[c] -= elapsed; clocks[c] < 0 {
+				return finish(loss(color), "time forfeit")
+			}
+			clocks[c] += tc.Increment
+		}
+		if result.BestMove == nil || !b.IsLegal(result.BestMove) {
+			return finish(loss(color), "illegal move")
+		}
+		b.Push(result.BestMove)
+		node = node.Add(result.BestMove)
+		if result, reason := a.update(ply, color, result.Score); result != chess.NoResult {
+			return finish(result, reason)
+		}
+	}
+}
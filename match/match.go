@@ -0,0 +1,208 @@
+// Package match 让两个引擎配置互相对局，统计成绩并用序贯概率比检验 (SPRT) 判断 Elo 是否提高。
+package match
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// Config 是一场比赛的设置，成绩以 Players[0] 为视角
+type Config struct {
+	Players [2]PlayerConfig
+	// Openings 是开局局面的 FEN，每个开局双方交换先后各下一局，为空时使用初始局面
+	Openings []string
+	// Games 是最多进行的对局数
+	Games int
+	// Concurrency 是同时进行的对局数，为 0 时使用 CPU 核数
+	Concurrency  int
+	TimeControl  TimeControl
+	Adjudication Adjudication
+	// SPRT 不为 nil 时检验得出结论后提前结束比赛
+	SPRT  *SPRT
+	Event string
+}
+
+// Match 进行一场比赛，OnGame 在每局结束后调用，调用是串行的
+type Match struct {
+	Config
+	OnGame func(round int, result *GameResult, stats Stats)
+
+	mu       sync.Mutex
+	stats    Stats
+	decision Decision
+}
+
+func New(config Config) *Match {
+	return &Match{Config: config}
+}
+
+// ReadOpenings 读取开局文件，每行一个 FEN，以 # 开头的行会被忽略
+func ReadOpenings(r io.Reader) ([]string, error) {
+	var openings []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fen := strings.TrimSpace(scanner.Text())
+		if fen == "" || strings.HasPrefix(fen, "#") {
+			continue
+		}
+		if _, err := chess.NewBoardFromFen(fen); err != nil {
+			return nil, fmt.Errorf("match: line %d: %v", line, err)
+		}
+		openings = append(openings, fen)
+	}
+	return openings, scanner.Err()
+}
+
+func LoadOpenings(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadOpenings(f)
+}
+
+// Stats 返回当前的成绩
+func (m *Match) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Decision 返回 SPRT 的结论，没有设置 SPRT 时总是 Continue
+func (m *Match) Decision() Decision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.decision
+}
+
+func (m *Match) done() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.decision != Continue
+}
+
+// record 记录第 round 局 (从 1 开始) 的结果
+func (m *Match) record(round int, result *GameResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	winner, ok := result.Result.Winner()
+	switch {
+	case !ok:
+		m.stats.Draws++
+	case (winner == chess.Red) == (result.Red == 0):
+		m.stats.Wins++
+	default:
+		m.stats.Losses++
+	}
+	if m.SPRT != nil && m.decision == Continue {
+		m.decision = m.SPRT.Decide(m.stats)
+	}
+	game := result.Game
+	if m.Event != "" {
+		game.SetTag("Event", m.Event)
+	}
+	game.SetTag("Date", time.Now().Format("2006.01.02"))
+	game.SetTag("Round", fmt.Sprint(round))
+	if m.OnGame != nil {
+		m.OnGame(round, result, m.stats)
+	}
+}
+
+// worker 持有一对引擎实例，依次下 rounds 中的对局
+type worker struct {
+	players [2]Player
+}
+
+func (w *worker) close() {
+	for _, p := range w.players {
+		if p != nil {
+			p.Close()
+		}
+	}
+}
+
+func (m *Match) play(w *worker, round int) (*GameResult, error) {
+	openings := m.Openings
+	if len(openings) == 0 {
+		openings = []string{chess.StartingFen}
+	}
+	fen := openings[(round-1)/2%len(openings)]
+	red := (round - 1) % 2
+	result, err := PlayGame(w.players[red], w.players[1-red], fen, m.TimeControl, m.Adjudication)
+	if err != nil {
+		return nil, err
+	}
+	result.Red = red
+	return result, nil
+}
+
+// Run 进行比赛直到下完所有对局或者 SPRT 得出结论，返回最终成绩
+func (m *Match) Run() (Stats, error) {
+	if m.Games <= 0 {
+		return Stats{}, errors.New("match: no games to play")
+	}
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > m.Games {
+		concurrency = m.Games
+	}
+
+	rounds := make(chan int)
+	errs := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := &worker{}
+			defer w.close()
+			for j, config := range m.Players {
+				p, err := config.New()
+				if err != nil {
+					errs <- err
+					return
+				}
+				w.players[j] = p
+			}
+			for round := range rounds {
+				result, err := m.play(w, round)
+				if err != nil {
+					errs <- err
+					return
+				}
+				m.record(round, result)
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for round := 1; round <= m.Games && !m.done(); round++ {
+		select {
+		case rounds <- round:
+		case err = <-errs:
+			break feed
+		}
+	}
+	close(rounds)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return m.Stats(), err
+}
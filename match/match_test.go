@@ -0,0 +1,203 @@
+package match
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tc, err := ParseTimeControl("10+0.1")
+	if err != nil || tc.Base.Seconds() != 10 || tc.Increment.Milliseconds() != 100 {
+		t.Fatalf("unexpected time control %+v %v", tc, err)
+	}
+	if _, err := ParseTimeControl("x+1"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestElo(t *testing.T) {
+	elo, _ := Stats{Wins: 76, Draws: 0, Losses: 24}.Elo()
+	if math.Abs(elo-200) > 1 {
+		t.Errorf("elo %f, want about 200", elo)
+	}
+	elo, margin := Stats{Wins: 30, Draws: 40, Losses: 30}.Elo()
+	if elo != 0 || margin <= 0 {
+		t.Errorf("elo %f +/- %f", elo, margin)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 10, Alpha: 0.05, Beta: 0.05}
+	lower, upper := sprt.Bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("bounds %f %f", lower, upper)
+	}
+	if d := sprt.Decide(Stats{Wins: 10, Draws: 10, Losses: 10}); d != Continue {
+		t.Errorf("decision %s, want continue", d)
+	}
+	if d := sprt.Decide(Stats{Wins: 3000, Draws: 4000, Losses: 2000}); d != AcceptH1 {
+		t.Errorf("decision %s, want H1", d)
+	}
+	if d := sprt.Decide(Stats{Wins: 2000, Draws: 4000, Losses: 2500}); d != AcceptH0 {
+		t.Errorf("decision %s, want H0", d)
+	}
+}
+
+func TestAdjudication(t *testing.T) {
+	a := adjudicator{Adjudication: Adjudication{ResignScore: 500, ResignMoves: 2}}
+	scores := []int{-600, 600, -700, 700}
+	var result chess.Result
+	for i, score := range scores {
+		color := i%2 == 0
+		if result, _ = a.update(i+1, color, score); result != chess.NoResult && i != len(scores)-1 {
+			t.Fatalf("adjudicated too early at ply %d", i+1)
+		}
+	}
+	if result != chess.BlackWins {
+		t.Errorf("result %s, want 0-1", result)
+	}
+
+	a = adjudicator{Adjudication: Adjudication{DrawScore: 10, DrawMoves: 2, DrawPly: 3}}
+	for i, score := range []int{0, 0, 5, -5, 0} {
+		result, _ = a.update(i+1, i%2 == 0, score)
+	}
+	if result != chess.NoResult {
+		t.Errorf("adjudicated draw before draw ply")
+	}
+	result, _ = a.update(6, false, 3)
+	if result != chess.Draw {
+		t.Errorf("result %s, want draw", result)
+	}
+}
+
+// script 循环走一串固定的着法
+type script struct {
+	moves []*chess.Move
+	i     int
+}
+
+func newScript(t *testing.T, moves ...string) *script {
+	t.Helper()
+	s := &script{}
+	for _, m := range moves {
+		move, err := chess.ParseMove(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.moves = append(s.moves, move)
+	}
+	return s
+}
+
+func (s *script) Name() string   { return "script" }
+func (s *script) NewGame() error { s.i = 0; return nil }
+func (s *script) Close() error   { return nil }
+
+func (s *script) Search(b *chess.Board, limits engine.Limits) (engine.Result, error) {
+	move := s.moves[s.i%len(s.moves)]
+	s.i++
+	return engine.Result{BestMove: move}, nil
+}
+
+func TestRepetition(t *testing.T) {
+	const fen = "4k4/9/9/9/9/9/9/9/9/R2K5 w - - 0 1"
+	// 红车每一步都将军，长将判负
+	result, err := PlayGame(newScript(t, "a0a9", "a9a8", "a8a9", "a9a8", "a8a9"), newScript(t, "e9e8", "e8e9"), fen, TimeControl{}, Adjudication{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Result != chess.BlackWins || result.Reason != "perpetual check" {
+		t.Errorf("perpetual check: %s %s", result.Result, result.Reason)
+	}
+	// 没有长将的重复局面不判和，由最大步数结束对局
+	result, err = PlayGame(newScript(t, "a0a1", "a1a0"), newScript(t, "e9e8", "e8e9"), fen, TimeControl{}, Adjudication{MaxPly: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Result != chess.Draw || result.Reason != "adjudication: maximum length" {
+		t.Errorf("repetition without checks: %s %s", result.Result, result.Reason)
+	}
+	// 没有判和规则时由 60 回合限制结束
+	result, err = PlayGame(newScript(t, "a0a1", "a1a0"), newScript(t, "e9e8", "e8e9"), fen, TimeControl{}, Adjudication{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Result != chess.Draw || result.Reason != "60-move rule" {
+		t.Errorf("repetition without checks: %s %s", result.Result, result.Reason)
+	}
+}
+
+func TestDrawReason(t *testing.T) {
+	for fen, want := range map[string]string{
+		"3akab2/9/9/9/9/9/9/9/9/3AKA3 w - - 0 1": "insufficient material",
+		"4k4/9/9/9/9/9/9/9/9/3KC4 w - - 0 1":     "dead draw",
+	} {
+		result, err := PlayGame(newScript(t, "a0a1"), newScript(t, "a9a8"), fen, TimeControl{}, Adjudication{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Result != chess.Draw || result.Reason != want {
+			t.Errorf("%s: %s %s, want draw by %s", fen, result.Result, result.Reason, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	openings, err := ReadOpenings(strings.NewReader(`# openings
+rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR w - - 0 1
+rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1
+`))
+	if err != nil || len(openings) != 2 {
+		t.Fatal(openings, err)
+	}
+	m := New(Config{
+		Players: [2]PlayerConfig{
+			{Name: "strong", Options: map[string]string{"Hash": "1"}},
+			{Name: "weak", Options: map[string]string{"Hash": "1", "SkillLevel": "1"}},
+		},
+		Openings:     openings,
+		Games:        4,
+		Concurrency:  2,
+		TimeControl:  TimeControl{Depth: 2},
+		Adjudication: Adjudication{ResignScore: 800, ResignMoves: 2, MaxPly: 100},
+		Event:        "test",
+	})
+	var results []*GameResult
+	m.OnGame = func(round int, result *GameResult, stats Stats) {
+		results = append(results, result)
+	}
+	stats, err := m.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games() != 4 || len(results) != 4 {
+		t.Fatalf("played %d games", stats.Games())
+	}
+	for _, result := range results {
+		round := result.Game.Tag("Round")
+		red := map[string]int{"1": 0, "2": 1, "3": 0, "4": 1}[round]
+		if result.Red != red || result.Game.Tag("Red") != m.Players[red].Name {
+			t.Errorf("round %s: wrong colours", round)
+		}
+		if (round == "3" || round == "4") && result.Game.Tag("FEN") != openings[1] {
+			t.Errorf("round %s: wrong opening %q", round, result.Game.Tag("FEN"))
+		}
+		if result.Result == chess.NoResult || result.Reason == "" {
+			t.Errorf("round %s: no result", round)
+		}
+		b, err := result.Game.Board()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, move := range result.Game.MainLine() {
+			if !b.IsLegal(move) {
+				t.Fatalf("round %s: illegal move %s", round, move)
+			}
+			b.Push(move)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package match
+
+import (
+	"fmt"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/external"
+)
+
+// Player 是参加比赛的一方，*external.Engine 直接满足这个接口
+type Player interface {
+	Name() string
+	NewGame() error
+	Search(b *chess.Board, limits engine.Limits) (engine.Result, error)
+	Close() error
+}
+
+// PlayerConfig 描述一个引擎配置，Path 为空或者为 "builtin" 时使用内置引擎
+type PlayerConfig struct {
+	Name     string
+	Path     string
+	Args     []string
+	Protocol external.Protocol
+	Options  map[string]string
+}
+
+func (c PlayerConfig) IsBuiltin() bool {
+	return c.Path == "" || c.Path == "builtin"
+}
+
+// New 启动一个新的引擎实例，每局比赛同时进行时每个工作线程使用自己的实例
+func (c PlayerConfig) New() (Player, error) {
+	if !c.IsBuiltin() {
+		e, err := external.Start(external.Config{
+			Path:        c.Path,
+			Args:        c.Args,
+			Protocol:    c.Protocol,
+			Options:     c.Options,
+			MaxRestarts: 3,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if c.Name == "" {
+			return e, nil
+		}
+		return &namedPlayer{Player: e, name: c.Name}, nil
+	}
+	e := engine.NewEngine()
+	if err := e.SetOptions(c.Options); err != nil {
+		return nil, fmt.Errorf("match: %v", err)
+	}
+	name := c.Name
+	if name == "" {
+		name = "gochess"
+	}
+	return &builtin{engine: e, name: name}, nil
+}
+
+type namedPlayer struct {
+	Player
+	name string
+}
+
+func (p *namedPlayer) Name() string {
+	return p.name
+}
+
+// builtin 把内置引擎包装成 Player
+type builtin struct {
+	engine *engine.Engine
+	name   string
+}
+
+func (p *builtin) Name() string {
+	return p.name
+}
+
+func (p *builtin) NewGame() error {
+	p.engine.NewGame()
+	return nil
+}
+
+func (p *builtin) Search(b *chess.Board, limits engine.Limits) (engine.Result, error) {
+	return p.engine.Search(b, limits), nil
+}
+
+func (p *builtin) Close() error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package match
+
+import (
+	"math"
+)
+
+// Stats 是第一个引擎视角的比赛成绩
+type Stats struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+func (s Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score 返回平均得分，胜 1 分，和 0.5 分
+func (s Stats) Score() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// variance 返回每局得分的方差
+func (s Stats) variance() float64 {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0
+	}
+	score := s.Score()
+	return (float64(s.Wins)*(1-score)*(1-score) +
+		float64(s.Draws)*(0.5-score)*(0.5-score) +
+		float64(s.Losses)*score*score) / n
+}
+
+func scoreToElo(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	} else if score >= 1 {
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+func eloToScore(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// Elo 返回 Elo 分差的估计值和 95% 置信区间的半宽
+func (s Stats) Elo() (elo float64, margin float64) {
+	score := s.Score()
+	elo = scoreToElo(score)
+	n := float64(s.Games())
+	if n == 0 {
+		return 0, 0
+	} else if score <= 0 || score >= 1 {
+		return elo, math.Inf(1)
+	}
+	delta := 1.959964 * math.Sqrt(s.variance()/n)
+	margin = (scoreToElo(score+delta) - scoreToElo(score-delta)) / 2
+	return elo, margin
+}
+
+// SPRT 检验 H0: Elo 分差为 Elo0 和 H1: Elo 分差为 Elo1，Alpha 和 Beta 是两类错误的概率
+type SPRT struct {
+	Elo0  float64
+	Elo1  float64
+	Alpha float64
+	Beta  float64
+}
+
+type Decision int
+
+const (
+	Continue Decision = iota
+	AcceptH0
+	AcceptH1
+)
+
+func (d Decision) String() string {
+	switch d {
+	case AcceptH0:
+		return "H0 accepted"
+	case AcceptH1:
+		return "H1 accepted"
+	}
+	return "continue"
+}
+
+// Bounds 返回对数似然比的下界和上界
+func (t SPRT) Bounds() (lower float64, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// LLR 用正态近似计算对数似然比：N (s1 - s0) (2s - s0 - s1) / 2σ²
+func (t SPRT) LLR(s Stats) float64 {
+	variance := s.variance()
+	if variance == 0 {
+		return 0
+	}
+	s0, s1 := eloToScore(t.Elo0), eloToScore(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*s.Score() - s0 - s1) / (2 * variance)
+}
+
+func (t SPRT) Decide(s Stats) Decision {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	if llr >= upper {
+		return AcceptH1
+	} else if llr <= lower {
+		return AcceptH0
+	}
+	return Continue
+}
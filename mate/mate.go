@@ -0,0 +1,221 @@
+// Package mate 求解排局中的杀棋问题：攻方在 N 步之内能否必然杀棋，可以要求攻方每一步都将军 (连将杀)。
+//
+// 搜索是带置换表的与或树深度优先搜索，攻方节点按迭代加深寻找最短的杀法，守方节点需要所有应着都被杀。
+// 象棋中被困毙也判负，所以守方无棋可走就算杀棋。
+package mate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+)
+
+var (
+	ErrNoMate    = errors.New("mate: no forced mate within the move limit")
+	ErrNodeLimit = errors.New("mate: node limit exceeded")
+)
+
+type Options struct {
+	// MaxMoves 是攻方最多走的步数
+	MaxMoves int
+	// ChecksOnly 为 true 时攻方每一步都必须将军
+	ChecksOnly bool
+	// MaxNodes 不为 0 时限制搜索的节点数
+	MaxNodes uint64
+}
+
+// Node 是解答树的一个节点。攻方着法的子节点是守方所有的应着，按抵抗的步数从多到少排列；
+// 守方着法的子节点只有一个，是攻方最快的杀法
+type Node struct {
+	Move *chess.Move
+	// Mate 是走完这一步之后攻方还需要走的步数，攻方着法包括它本身
+	Mate     int
+	Children []*Node
+}
+
+// Solution 是一个问题的解答，Tree 是根局面，它唯一的子节点是攻方的第一步
+type Solution struct {
+	Moves int
+	Tree  *Node
+	Nodes uint64
+}
+
+// MainLine 返回双方的最佳着法：攻方走最快的杀法，守方选择抵抗最久的应着
+func (s *Solution) MainLine() []*chess.Move {
+	var line []*chess.Move
+	for node := s.Tree; len(node.Children) > 0; {
+		node = node.Children[0]
+		line = append(line, node.Move)
+	}
+	return line
+}
+
+// Write 把解答树按缩进写出，每行一步
+func (s *Solution) Write(w io.Writer) error {
+	var write func(node *Node, depth int) error
+	write = func(node *Node, depth int) error {
+		for _, child := range node.Children {
+			if _, err := fmt.Fprintf(w, "%s%s (%d)\n", strings.Repeat("  ", depth), child.Move, child.Mate); err != nil {
+				return err
+			}
+			if err := write(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return write(s.Tree, 0)
+}
+
+type solver struct {
+	board   *chess.Board
+	options Options
+	nodes   uint64
+	aborted bool
+	// proven 是攻方走子时已经证明的最短杀棋步数，disproven 是已经证明不能杀棋的最大步数
+	proven    map[uint64]int
+	disproven map[uint64]int
+}
+
+// Solve 求解 b 中走子方的杀棋问题，b 本身不会被修改。没有杀法时返回 ErrNoMate
+func Solve(b *chess.Board, options Options) (*Solution, error) {
+	s := &solver{
+		board:     b.Copy(),
+		options:   options,
+		proven:    map[uint64]int{},
+		disproven: map[uint64]int{},
+	}
+	n := s.attack(options.MaxMoves)
+	if s.aborted {
+		return nil, ErrNodeLimit
+	}
+	if n == 0 {
+		return nil, ErrNoMate
+	}
+	// 生成解答树时只会访问已经证明过的局面，不再限制节点数
+	s.options.MaxNodes = 0
+	tree := &Node{Mate: n, Children: []*Node{s.build(n)}}
+	return &Solution{Moves: n, Tree: tree, Nodes: s.nodes}, nil
+}
+
+// attackerMoves 返回攻方的合法着法，将军的着法排在前面，然后是吃子的着法
+func (s *solver) attackerMoves() []*chess.Move {
+	var moves []*chess.Move
+	priority := map[*chess.Move]int{}
+	for _, move := range s.board.LegalMoves() {
+		capture := s.board.IsCapture(move)
+		s.board.Push(move)
+		check := s.board.IsCheck()
+		s.board.Pop()
+		if s.options.ChecksOnly && !check {
+			continue
+		}
+		if check {
+			priority[move] += 2
+		}
+		if capture {
+			priority[move]++
+		}
+		moves = append(moves, move)
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return priority[moves[i]] > priority[moves[j]]
+	})
+	return moves
+}
+
+func (s *solver) addNode() bool {
+	s.nodes++
+	if s.options.MaxNodes > 0 && s.nodes > s.options.MaxNodes {
+		s.aborted = true
+	}
+	return !s.aborted
+}
+
+// attack 返回攻方在 n 步之内杀棋的最少步数，0 表示不能杀棋
+func (s *solver) attack(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	key := s.board.Hash()
+	if m, ok := s.proven[key]; ok && m <= n {
+		return m
+	}
+	if d, ok := s.disproven[key]; ok && d >= n {
+		return 0
+	}
+	if !s.addNode() {
+		return 0
+	}
+	moves := s.attackerMoves()
+	// 从短到长依次尝试，找到的第一个杀法就是最短的
+	for k := 1; k <= n; k++ {
+		if d, ok := s.disproven[key]; ok && d >= k {
+			continue
+		}
+		for _, move := range moves {
+			s.board.Push(move)
+			mated := s.defend(k)
+			s.board.Pop()
+			if s.aborted {
+				return 0
+			}
+			if mated {
+				s.proven[key] = k
+				return k
+			}
+		}
+		s.disproven[key] = k
+	}
+	return 0
+}
+
+// defend 判断守方走子时，攻方是否在包括刚走的一步在内的 n 步之内杀棋
+func (s *solver) defend(n int) bool {
+	if !s.addNode() {
+		return false
+	}
+	moves := s.board.LegalMoves()
+	if len(moves) == 0 {
+		return true
+	}
+	if n <= 1 {
+		return false
+	}
+	for _, move := range moves {
+		s.board.Push(move)
+		m := s.attack(n - 1)
+		s.board.Pop()
+		if m == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// build 生成攻方在 n 步之内杀棋的解答树，返回攻方第一步的节点
+func (s *solver) build(n int) *Node {
+	for _, move := range s.attackerMoves() {
+		s.board.Push(move)
+		if s.defend(n) {
+			node := &Node{Move: move, Mate: n}
+			for _, reply := range s.board.LegalMoves() {
+				s.board.Push(reply)
+				m := s.attack(n - 1)
+				node.Children = append(node.Children, &Node{Move: reply, Mate: m, Children: []*Node{s.build(m)}})
+				s.board.Pop()
+			}
+			sort.SliceStable(node.Children, func(i, j int) bool {
+				return node.Children[i].Mate > node.Children[j].Mate
+			})
+			s.board.Pop()
+			return node
+		}
+		s.board.Pop()
+	}
+	return nil
+}
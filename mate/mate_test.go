@@ -0,0 +1,109 @@
+package mate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// verify 检查攻方着法节点 node 的解答树：守方的所有应着都出现在子节点中，并且在 node.Mate 步之内被杀
+func verify(t *testing.T, b *chess.Board, node *Node, checksOnly bool) {
+	t.Helper()
+	if !b.IsLegal(node.Move) {
+		t.Fatalf("%s: illegal attacking move %s", b.Fen(), node.Move)
+	}
+	b.Push(node.Move)
+	defer b.Pop()
+	if checksOnly && !b.IsCheck() {
+		t.Fatalf("%s: %s is not a check", b.Fen(), node.Move)
+	}
+	replies := b.LegalMoves()
+	if len(replies) != len(node.Children) {
+		t.Fatalf("%s: %d replies in tree, want %d", b.Fen(), len(node.Children), len(replies))
+	}
+	if node.Mate == 1 && len(replies) > 0 {
+		t.Fatalf("%s: not mated after %s", b.Fen(), node.Move)
+	}
+	for i, reply := range node.Children {
+		if reply.Mate >= node.Mate || (i > 0 && reply.Mate > node.Children[i-1].Mate) {
+			t.Fatalf("%s: bad mate count %d after %s", b.Fen(), reply.Mate, reply.Move)
+		}
+		b.Push(reply.Move)
+		verify(t, b, reply.Children[0], checksOnly)
+		b.Pop()
+	}
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		fen        string
+		maxMoves   int
+		checksOnly bool
+		moves      int
+		first      string
+	}{
+		{"4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1", 3, false, 1, "a0a9"},
+		{"6b2/7N1/4k4/9/9/9/r8/9/9/3K3R1 w - - 0 1", 3, true, 2, "h0e0"},
+		{"2b1r4/4k4/9/9/9/3N5/9/R8/5K3/9 w - - 0 1", 3, true, 3, "a2a8"},
+		{"2b1r4/4k4/9/9/9/3N5/9/R8/5K3/9 w - - 0 1", 3, false, 3, ""},
+		// 不要求将军时可以困毙对方
+		{"3k5/9/9/3P5/9/9/2R6/4K4/9/9 w - - 0 1", 3, true, 3, "c3c9"},
+		{"3k5/9/9/3P5/9/9/2R6/4K4/9/9 w - - 0 1", 3, false, 1, ""},
+	}
+	for _, test := range tests {
+		b, err := chess.NewBoardFromFen(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		solution, err := Solve(b, Options{MaxMoves: test.maxMoves, ChecksOnly: test.checksOnly})
+		if err != nil {
+			t.Errorf("%s: %v", test.fen, err)
+			continue
+		}
+		if solution.Moves != test.moves {
+			t.Errorf("%s: mate in %d, want %d", test.fen, solution.Moves, test.moves)
+		}
+		line := solution.MainLine()
+		if len(line) != 2*solution.Moves-1 {
+			t.Errorf("%s: main line %v", test.fen, line)
+		}
+		if test.first != "" && line[0].String() != test.first {
+			t.Errorf("%s: first move %s, want %s", test.fen, line[0], test.first)
+		}
+		verify(t, b, solution.Tree.Children[0], test.checksOnly)
+		if b.Fen() != test.fen {
+			t.Errorf("board changed to %s", b.Fen())
+		}
+	}
+}
+
+func TestNoMate(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("3akab2/9/9/9/9/9/9/9/9/R3K3R w - - 0 1")
+	if _, err := Solve(b, Options{MaxMoves: 3, ChecksOnly: true}); err != ErrNoMate {
+		t.Errorf("got %v, want ErrNoMate", err)
+	}
+	b, _ = chess.NewBoardFromFen("2b1r4/4k4/9/9/9/3N5/9/R8/5K3/9 w - - 0 1")
+	if _, err := Solve(b, Options{MaxMoves: 2, ChecksOnly: true}); err != ErrNoMate {
+		t.Errorf("got %v, want ErrNoMate", err)
+	}
+	if _, err := Solve(b, Options{MaxMoves: 3, MaxNodes: 10}); err != ErrNodeLimit {
+		t.Errorf("got %v, want ErrNodeLimit", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	b, _ := chess.NewBoardFromFen("6b2/7N1/4k4/9/9/9/r8/9/9/3K3R1 w - - 0 1")
+	solution, err := Solve(b, Options{MaxMoves: 2, ChecksOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := solution.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "h0e0 (2)\n  a3e3 (1)\n    e0e3 (1)\n"; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("got\n%s\nwant prefix\n%s", buf.String(), want)
+	}
+}
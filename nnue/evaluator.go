@@ -0,0 +1,131 @@
+package nnue
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+// accumulator 保存红方和黑方两个视角的隐藏层输入
+type accumulator [2][]int16
+
+func perspectiveIndex(perspective bool) int {
+	if perspective == chess.Red {
+		return 0
+	}
+	return 1
+}
+
+// Evaluator 跟随局面的走子和悔棋增量维护累加器，每个搜索线程使用自己的 Evaluator
+type Evaluator struct {
+	net   *Network
+	stack []accumulator
+	top   int
+}
+
+func (n *Network) NewEvaluator(b *chess.Board) *Evaluator {
+	ev := &Evaluator{net: n}
+	ev.Reset(b)
+	return ev
+}
+
+// Reset 根据局面 b 重新计算累加器，清空之前的走子记录
+func (ev *Evaluator) Reset(b *chess.Board) {
+	ev.top = 0
+	acc := ev.at(0)
+	for p, perspective := range []bool{chess.Red, chess.Black} {
+		copy(acc[p], ev.net.FeatureBias)
+		for _, sq := range chess.ScanReversed(b.Occupied()) {
+			piece := b.PieceAt(sq)
+			add(acc[p], ev.weights(Feature(perspective, piece.PieceType, piece.Color, sq)))
+		}
+	}
+}
+
+func (ev *Evaluator) at(i int) accumulator {
+	for len(ev.stack) <= i {
+		hidden := ev.net.Hidden
+		ev.stack = append(ev.stack, accumulator{make([]int16, hidden), make([]int16, hidden)})
+	}
+	return ev.stack[i]
+}
+
+func (ev *Evaluator) weights(feature int) []int16 {
+	hidden := ev.net.Hidden
+	return ev.net.FeatureWeights[feature*hidden : (feature+1)*hidden]
+}
+
+// Push 在 b.Push(move) 之前调用，根据走子前的局面更新累加器，空着不改变累加器
+func (ev *Evaluator) Push(b *chess.Board, move *chess.Move) {
+	prev := ev.stack[ev.top]
+	ev.top++
+	acc := ev.at(ev.top)
+	if *move == chess.NullMove {
+		copy(acc[0], prev[0])
+		copy(acc[1], prev[1])
+		return
+	}
+	piece := b.PieceAt(move.FromSquare)
+	captured := b.PieceAt(move.ToSquare)
+	for p, perspective := range []bool{chess.Red, chess.Black} {
+		from := ev.weights(Feature(perspective, piece.PieceType, piece.Color, move.FromSquare))
+		to := ev.weights(Feature(perspective, piece.PieceType, piece.Color, move.ToSquare))
+		if captured == nil {
+			addSub(acc[p], prev[p], to, from)
+		} else {
+			victim := ev.weights(Feature(perspective, captured.PieceType, captured.Color, move.ToSquare))
+			addSubSub(acc[p], prev[p], to, from, victim)
+		}
+	}
+}
+
+// Pop 在 b.Pop() 时调用，恢复上一步的累加器
+func (ev *Evaluator) Pop() {
+	ev.top--
+}
+
+// Evaluate 返回以 turn 一方为视角的评估分数，turn 必须是当前局面的走子方
+func (ev *Evaluator) Evaluate(turn bool) int {
+	acc := ev.stack[ev.top]
+	n := ev.net
+	us, them := acc[perspectiveIndex(turn)], acc[perspectiveIndex(!turn)]
+	sum := int64(n.OutputBias)
+	sum += dot(us, n.OutputWeights[:n.Hidden])
+	sum += dot(them, n.OutputWeights[n.Hidden:])
+	return int(sum * Scale / (QA * QB))
+}
+
+// 以下循环只做逐元素运算，先截取相同长度以去掉边界检查，便于编译器生成紧凑的代码
+
+func add(acc []int16, w []int16) {
+	w = w[:len(acc)]
+	for i := range acc {
+		acc[i] += w[i]
+	}
+}
+
+func addSub(dst []int16, src []int16, a []int16, s []int16) {
+	src, a, s = src[:len(dst)], a[:len(dst)], s[:len(dst)]
+	for i := range dst {
+		dst[i] = src[i] + a[i] - s[i]
+	}
+}
+
+func addSubSub(dst []int16, src []int16, a []int16, s1 []int16, s2 []int16) {
+	src, a, s1, s2 = src[:len(dst)], a[:len(dst)], s1[:len(dst)], s2[:len(dst)]
+	for i := range dst {
+		dst[i] = src[i] + a[i] - s1[i] - s2[i]
+	}
+}
+
+func dot(acc []int16, w []int16) int64 {
+	w = w[:len(acc)]
+	sum := int64(0)
+	for i, v := range acc {
+		if v < 0 {
+			v = 0
+		} else if v > QA {
+			v = QA
+		}
+		sum += int64(v) * int64(w[i])
+	}
+	return sum
+}
@@ -0,0 +1,130 @@
+package nnue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 网络结构：
+//
+//	输入    每一方视角 1260 个特征 = 己方/对方 × 7 种棋子 × 90 个格子，对方视角的局面上下翻转
+//	隐藏层  两个视角各一个长度为 Hidden 的累加器，共享同一组权重，可以随着走子增量更新
+//	输出    走子方在前拼接两个累加器，经过 [0, QA] 的截断 ReLU 后线性组合成一个分数
+//
+// 权重文件格式（小端序）：
+//
+//	magic          [4]byte "GCNN"
+//	version        uint32
+//	hidden         uint32
+//	featureBias    [hidden]int16
+//	featureWeights [Inputs][hidden]int16
+//	outputWeights  [2 * hidden]int16
+//	outputBias     int32
+//
+// 评估分数为 (Σ crelu(acc) × outputWeights + outputBias) × Scale / (QA × QB)，以走子方为视角。
+const (
+	magic   = "GCNN"
+	version = 1
+
+	Inputs = 2 * 7 * 90
+	QA     = 255
+	QB     = 64
+	Scale  = 400
+
+	// MaxHidden 限制读取的隐藏层大小，防止错误的文件申请过多内存
+	MaxHidden = 4096
+)
+
+var ErrInvalidNetwork = errors.New("nnue: invalid network file")
+
+type Network struct {
+	Hidden         int
+	FeatureBias    []int16
+	FeatureWeights []int16
+	OutputWeights  []int16
+	OutputBias     int32
+}
+
+func NewNetwork(hidden int) *Network {
+	return &Network{
+		Hidden:         hidden,
+		FeatureBias:    make([]int16, hidden),
+		FeatureWeights: make([]int16, Inputs*hidden),
+		OutputWeights:  make([]int16, 2*hidden),
+	}
+}
+
+// Feature 返回视角 perspective 下 color 方位于 sq 的棋子对应的输入下标
+func Feature(perspective bool, pieceType uint8, color bool, sq uint8) int {
+	if perspective == chess.Black {
+		sq = chess.SquareMirror(sq)
+	}
+	index := (chess.SquareRank(sq)-3)*9 + chess.SquareFile(sq) - 3
+	side := 0
+	if color != perspective {
+		side = 1
+	}
+	return (side*7+int(pieceType)-1)*90 + index
+}
+
+func Load(path string) (*Network, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(bufio.NewReader(f))
+}
+
+func Read(r io.Reader) (*Network, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:4]) != magic ||
+		binary.LittleEndian.Uint32(header[4:8]) != version {
+		return nil, ErrInvalidNetwork
+	}
+	hidden := binary.LittleEndian.Uint32(header[8:])
+	if hidden == 0 || hidden > MaxHidden {
+		return nil, ErrInvalidNetwork
+	}
+	n := NewNetwork(int(hidden))
+	for _, data := range []interface{}{n.FeatureBias, n.FeatureWeights, n.OutputWeights, &n.OutputBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, ErrInvalidNetwork
+		}
+	}
+	return n, nil
+}
+
+func (n *Network) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := n.Write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (n *Network) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	for _, data := range []interface{}{uint32(version), uint32(n.Hidden), n.FeatureBias, n.FeatureWeights, n.OutputWeights, n.OutputBias} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package nnue
+
+import (
+	"bytes"
+	"flag"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/material.gcnn")
+
+// materialValues 是测试网络中每种棋子的子力，放大 20 倍后约为百分制的分数
+var materialValues = [8]int16{
+	chess.Pawn:    5,
+	chess.Cannon:  22,
+	chess.Rook:    45,
+	chess.Knight:  20,
+	chess.Bishop:  10,
+	chess.Advisor: 10,
+}
+
+// materialNetwork 构造一个只计算子力差的小网络：两个隐藏单元分别累加己方和对方的子力
+func materialNetwork() *Network {
+	n := NewNetwork(2)
+	for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+		for sq := 0; sq < 90; sq++ {
+			own := (int(pieceType)-1)*90 + sq
+			their := (7+int(pieceType)-1)*90 + sq
+			n.FeatureWeights[own*2] = materialValues[pieceType]
+			n.FeatureWeights[their*2+1] = materialValues[pieceType]
+		}
+	}
+	// 20 × (己方 - 对方) = sum × Scale / (QA × QB)
+	n.OutputWeights[0] = 20 * QA * QB / Scale
+	n.OutputWeights[1] = -20 * QA * QB / Scale
+	return n
+}
+
+func randomNetwork(hidden int, seed int64) *Network {
+	rng := rand.New(rand.NewSource(seed))
+	n := NewNetwork(hidden)
+	for i := range n.FeatureBias {
+		n.FeatureBias[i] = int16(rng.Intn(64))
+	}
+	for i := range n.FeatureWeights {
+		n.FeatureWeights[i] = int16(rng.Intn(33) - 16)
+	}
+	for i := range n.OutputWeights {
+		n.OutputWeights[i] = int16(rng.Intn(257) - 128)
+	}
+	n.OutputBias = int32(rng.Intn(2001) - 1000)
+	return n
+}
+
+// reference 不使用累加器，直接按照网络结构计算评估分数
+func reference(n *Network, b *chess.Board) int {
+	sum := int64(n.OutputBias)
+	for k, perspective := range []bool{b.Turn(), !b.Turn()} {
+		for i := 0; i < n.Hidden; i++ {
+			v := int64(n.FeatureBias[i])
+			for _, sq := range chess.ScanReversed(b.Occupied()) {
+				piece := b.PieceAt(sq)
+				v += int64(n.FeatureWeights[Feature(perspective, piece.PieceType, piece.Color, sq)*n.Hidden+i])
+			}
+			if v < 0 {
+				v = 0
+			} else if v > QA {
+				v = QA
+			}
+			sum += v * int64(n.OutputWeights[k*n.Hidden+i])
+		}
+	}
+	return int(sum * Scale / (QA * QB))
+}
+
+func TestMaterialNetwork(t *testing.T) {
+	if *update {
+		if err := materialNetwork().Save("testdata/material.gcnn"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := Load("testdata/material.gcnn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, materialNetwork()) {
+		t.Fatal("testdata/material.gcnn differs from materialNetwork, run with -update")
+	}
+	b := chess.NewBoard()
+	ev := n.NewEvaluator(b)
+	if score := ev.Evaluate(b.Turn()); score != 0 {
+		t.Errorf("starting position: got %d, want 0", score)
+	}
+	// 红炮换黑马，红方少了炮和马的差价
+	for _, text := range []string{"h2h9", "i9h9"} {
+		move, _ := chess.ParseMove(text)
+		ev.Push(b, move)
+		b.Push(move)
+	}
+	if score := ev.Evaluate(b.Turn()); score != 20*(20-22) {
+		t.Errorf("got %d, want %d", score, 20*(20-22))
+	}
+}
+
+func TestIncrementalUpdate(t *testing.T) {
+	n := randomNetwork(16, 1)
+	rng := rand.New(rand.NewSource(2))
+	for game := 0; game < 4; game++ {
+		b := chess.NewBoard()
+		ev := n.NewEvaluator(b)
+		for ply := 0; ply < 120; ply++ {
+			moves := b.LegalMoves()
+			if len(moves) == 0 {
+				break
+			}
+			move := moves[rng.Intn(len(moves))]
+			if rng.Intn(10) == 0 {
+				move = &chess.NullMove
+			}
+			ev.Push(b, move)
+			if *move == chess.NullMove {
+				b.PushNull()
+			} else {
+				b.Push(move)
+			}
+			if got, want := ev.Evaluate(b.Turn()), reference(n, b); got != want {
+				t.Fatalf("%s: incremental %d, reference %d", b.Fen(), got, want)
+			}
+			// 偶尔悔棋检查累加器能正确恢复
+			if rng.Intn(4) == 0 {
+				ev.Pop()
+				b.Pop()
+				if got, want := ev.Evaluate(b.Turn()), reference(n, b); got != want {
+					t.Fatalf("%s after pop: incremental %d, reference %d", b.Fen(), got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	n := randomNetwork(8, 3)
+	buf := &bytes.Buffer{}
+	if err := n.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n, loaded) {
+		t.Fatal("network changed after write and read")
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()[:100])); err != ErrInvalidNetwork {
+		t.Errorf("truncated file: got %v, want ErrInvalidNetwork", err)
+	}
+	if _, err := Load("testdata/missing.gcnn"); !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist", err)
+	}
+}
+
+func BenchmarkEvaluator(b *testing.B) {
+	n := randomNetwork(256, 1)
+	board := chess.NewBoard()
+	ev := n.NewEvaluator(board)
+	move, _ := chess.ParseMove("h2e2")
+	for i := 0; i < b.N; i++ {
+		ev.Push(board, move)
+		board.Push(move)
+		ev.Evaluate(board.Turn())
+		board.Pop()
+		ev.Pop()
+	}
+}
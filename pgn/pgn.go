@@ -0,0 +1,38 @@
+// Package pgn 读写象棋的 PGN 棋谱。着法可以用 ICCS 坐标、WXF 或者中文记谱，由 Format 标签指定，
+// 支持注释和变着，读取时会在棋盘上重放每一步检查着法是否合法。
+package pgn
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+// Format 标签可以取的值
+const (
+	FormatICCS    = "ICCS"
+	FormatWXF     = "WXF"
+	FormatChinese = "Chinese"
+)
+
+// formatMove 按照 format 记录着法，不认识的格式使用 ICCS 坐标
+func formatMove(b *chess.Board, move *chess.Move, format string) string {
+	switch format {
+	case FormatWXF:
+		return b.WXF(move)
+	case FormatChinese:
+		return b.Chinese(move)
+	}
+	return move.String()
+}
+
+// parseMove 按照 format 解析着法并检查是否合法，format 为空时根据着法的写法判断格式
+func parseMove(b *chess.Board, s string, format string) (*chess.Move, error) {
+	switch format {
+	case "":
+		return b.ParseMove(s)
+	case FormatWXF:
+		return b.ParseWXF(s)
+	case FormatChinese:
+		return b.ParseChinese(s)
+	}
+	return b.ParseICCS(s)
+}
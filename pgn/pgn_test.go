@@ -0,0 +1,270 @@
+package pgn
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+func mustParse(t *testing.T, moves ...string) []*chess.Move {
+	t.Helper()
+	var result []*chess.Move
+	for _, s := range moves {
+		move, err := chess.ParseMove(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, move)
+	}
+	return result
+}
+
+func write(t *testing.T, g *game.Game) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := Write(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWrite(t *testing.T) {
+	g := game.NewGame()
+	g.Result = chess.RedWins
+	g.SetTag("Red", `A "B"`)
+	g.SetTag("FEN", "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1")
+	node := g.Root
+	for _, move := range mustParse(t, "h9g7", "h0g2", "i9h9") {
+		node = node.Add(move)
+	}
+	want := `[Event "?"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[Red "A \"B\""]
+[Black "?"]
+[Result "1-0"]
+[Format "ICCS"]
+[FEN "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1"]
+
+1... h9g7 2. h0g2 i9h9 1-0
+
+`
+	if got := write(t, g); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteWraps(t *testing.T) {
+	g := game.NewGame()
+	node := g.Root
+	for i := 0; i < 30; i++ {
+		for _, move := range mustParse(t, "h0g2", "h9g7", "g2h0", "g7h9") {
+			node = node.Add(move)
+		}
+	}
+	for _, line := range strings.Split(write(t, g), "\n") {
+		if len(line) > 80 {
+			t.Errorf("line too long: %q", line)
+		}
+	}
+}
+
+const annotated = `[Event "Test"]
+[Red "Red"]
+[Black "Black"]
+[Result "1/2-1/2"]
+
+{Opening} 1. h2e2 $1 h9g7 (1... b7e7 {Same-side cannons} 2. h0g2 (2. b0c2) b9c7) 2. h0g2 {Main} i9h9
+3. i0h0 ; rook out
+c6c5! 1/2-1/2
+`
+
+func TestRead(t *testing.T) {
+	g, err := NewReader(strings.NewReader(annotated)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Tag("Event") != "Test" || g.Result != chess.Draw {
+		t.Errorf("unexpected tags %v result %s", g.Tags, g.Result)
+	}
+	if !reflect.DeepEqual(g.MainLine(), mustParse(t, "h2e2", "h9g7", "h0g2", "i9h9", "i0h0", "c6c5")) {
+		t.Errorf("main line %v", g.MainLine())
+	}
+	root := g.Root
+	first := root.Children[0]
+	if root.Comment != "Opening" || !reflect.DeepEqual(first.NAGs, []int{1}) {
+		t.Errorf("root comment %q, nags %v", root.Comment, first.NAGs)
+	}
+	if len(first.Children) != 2 {
+		t.Fatalf("%d replies to the first move, want 2", len(first.Children))
+	}
+	variation := first.Children[1]
+	if variation.Move.String() != "b7e7" || variation.Comment != "Same-side cannons" {
+		t.Errorf("variation %s %q", variation.Move, variation.Comment)
+	}
+	if v := variation.Children[0]; len(v.Children) != 1 || len(variation.Children) != 2 || variation.Children[1].Move.String() != "b0c2" {
+		t.Errorf("nested variation not read correctly")
+	}
+	line := g.Root.Children[0].Children[0].Children[0].Children[0].Children[0]
+	if line.Comment != "rook out" || !reflect.DeepEqual(line.Children[0].NAGs, []int{1}) {
+		t.Errorf("comment %q, nags %v", line.Comment, line.Children[0].NAGs)
+	}
+}
+
+// 两条纵线上都有重叠的兵，前后之外还要写出纵线才能读回
+const tandemPawns = `[FEN "3k5/9/9/2P3P2/2P3P2/9/9/9/9/4K4 w - - 0 1"]
+
+1. c6c7 (1. g6g7 d9d8 2. c6c7) d9d8 2. g6g7 d8d9 3. g5h5 *
+`
+
+// 注释中的 } 和 \ 要转义
+const braces = `{C:\\games\\} 1. h2e2 {a \} b} h9g7 *
+`
+
+// 三种格式写出后再读入，着法树应该完全相同
+func TestRoundTrip(t *testing.T) {
+	for _, input := range []string{annotated, tandemPawns, braces} {
+		g, err := NewReader(strings.NewReader(input)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := write(t, g)
+		for _, format := range []string{FormatWXF, FormatChinese, FormatICCS} {
+			g.SetTag("Format", format)
+			text := write(t, g)
+			again, err := NewReader(strings.NewReader(text)).Read()
+			if err != nil {
+				t.Fatalf("%s: %v\n%s", format, err, text)
+			}
+			again.SetTag("Format", FormatICCS)
+			g.SetTag("Format", FormatICCS)
+			if got := write(t, again); got != want {
+				t.Errorf("%s: got\n%s\nwant\n%s", format, got, want)
+			}
+		}
+	}
+}
+
+func TestReadNotations(t *testing.T) {
+	want := mustParse(t, "h2e2", "h9g7", "h0g2", "i9h9")
+	for _, text := range []string{
+		"[Format \"WXF\"]\n1. C2.5 H8+7 2. H2+3 R9.8 *",
+		"[Format \"Chinese\"]\n1. 炮二平五 马８进７ 2. 马二进三 车９平８ *",
+		"1. 炮二平五 馬8進7 2. 傌二进三 車9平8 *",
+		"1. H2-E2 H9-G7 2. H0-G2 I9-H9 *",
+	} {
+		g, err := NewReader(strings.NewReader(text)).Read()
+		if err != nil {
+			t.Errorf("%q: %v", text, err)
+			continue
+		}
+		if !reflect.DeepEqual(g.MainLine(), want) {
+			t.Errorf("%q: main line %v", text, g.MainLine())
+		}
+	}
+}
+
+func TestReadMultiple(t *testing.T) {
+	input := `[Event "1"]
+1. h2e2 h9g7 1-0
+
+[Event "2"]
+1. h2e2 h9h0 0-1
+
+[Event "3"]
+1. b2e2
+
+[Event "4"]
+1. h0g2 *
+`
+	r := NewReader(strings.NewReader(input))
+	var events []string
+	errors := 0
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			errors++
+			continue
+		}
+		events = append(events, g.Tag("Event"))
+	}
+	if errors != 1 || !reflect.DeepEqual(events, []string{"1", "3", "4"}) {
+		t.Errorf("read %v with %d errors", events, errors)
+	}
+}
+
+func TestReadComments(t *testing.T) {
+	g, err := NewReader(strings.NewReader(braces)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Root.Comment != `C:\games\` || g.Root.Children[0].Comment != "a } b" {
+		t.Errorf("comments %q %q", g.Root.Comment, g.Root.Children[0].Comment)
+	}
+	// 其他软件写出的反斜杠保持不变
+	g, err = NewReader(strings.NewReader(`{a\b} 1. h2e2 *`)).Read()
+	if err != nil || g.Root.Comment != `a\b` {
+		t.Errorf("comment %q, %v", g.Root.Comment, err)
+	}
+}
+
+// 语法错误之后跳到行首的下一个标签继续读取
+func TestReadSyntaxError(t *testing.T) {
+	input := `[Event "1"]
+1. h2e2 h9g7) 2. h0g2 [x] 1-0
+
+[Event "2"]
+1. h2e2 (1. b2e2 *
+
+[Event "3"]
+1. h2e2 *
+
+[Event "4
+`
+	r := NewReader(strings.NewReader(input))
+	var events []string
+	errors := 0
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			errors++
+			continue
+		}
+		events = append(events, g.Tag("Event"))
+	}
+	if errors != 3 || !reflect.DeepEqual(events, []string{"3"}) {
+		t.Errorf("read %v with %d errors", events, errors)
+	}
+}
+
+func TestReadMany(t *testing.T) {
+	const count = 20000
+	g := "[Event \"x\"]\n[Result \"1-0\"]\n\n1. h2e2 h9g7 2. h0g2 i9h9 3. i0h0 1-0\n\n"
+	r := NewReader(strings.NewReader(strings.Repeat(g, count)))
+	n := 0
+	for {
+		g, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if len(g.MainLine()) != 5 {
+			t.Fatalf("game %d: %d moves", n+1, len(g.MainLine()))
+		}
+		n++
+	}
+	if n != count {
+		t.Errorf("read %d games, want %d", n, count)
+	}
+}
@@ -0,0 +1,359 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+var ErrSyntax = errors.New("pgn: syntax error")
+
+// errUnterminated 表示变着还没有结束就遇到了下一局的标签
+var errUnterminated = errors.New("pgn: unterminated variation")
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenComment
+	tokenNAG
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// Reader 从输入中逐局读取棋谱，适合读取包含大量对局的文件
+type Reader struct {
+	r *bufio.Reader
+	// games 是已经读取的对局数，用于错误信息
+	games int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+func (r *Reader) peek() (rune, error) {
+	c, _, err := r.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	return c, r.r.UnreadRune()
+}
+
+// skipSpace 跳过空白、字节顺序标记和以 % 开头的行
+func (r *Reader) skipSpace() error {
+	lineStart := true
+	for {
+		c, _, err := r.r.ReadRune()
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '\n':
+			lineStart = true
+		case unicode.IsSpace(c) || c == '\uFEFF':
+		case c == '%' && lineStart:
+			if _, err := r.r.ReadString('\n'); err != nil {
+				return err
+			}
+		default:
+			return r.r.UnreadRune()
+		}
+	}
+}
+
+// readUntil 读取到 delim 为止的内容，不包括 delim
+func (r *Reader) readUntil(delim rune) (string, error) {
+	var sb strings.Builder
+	for {
+		c, _, err := r.r.ReadRune()
+		if err != nil {
+			return sb.String(), err
+		}
+		if c == delim {
+			return sb.String(), nil
+		}
+		sb.WriteRune(c)
+	}
+}
+
+// readComment 读取 { 之后到 } 为止的注释，\} 和 \\ 是转义的 } 和 \
+func (r *Reader) readComment() (string, error) {
+	var sb strings.Builder
+	for {
+		c, _, err := r.r.ReadRune()
+		if err != nil {
+			return sb.String(), err
+		}
+		switch c {
+		case '}':
+			return sb.String(), nil
+		case '\\':
+			if next, err := r.peek(); err == nil && (next == '}' || next == '\\') {
+				c, _, _ = r.r.ReadRune()
+			}
+		}
+		sb.WriteRune(c)
+	}
+}
+
+// skipGame 在出错之后跳过这一局剩下的内容，直到行首的下一个标签
+func (r *Reader) skipGame() {
+	lineStart := false
+	for {
+		c, err := r.peek()
+		if err != nil || c == '[' && lineStart {
+			return
+		}
+		r.r.ReadRune()
+		switch {
+		case c == '\n':
+			lineStart = true
+		case !unicode.IsSpace(c):
+			lineStart = false
+		}
+	}
+}
+
+func (r *Reader) readTag() (game.Tag, error) {
+	r.r.ReadRune()
+	name, err := r.readUntil('"')
+	if err != nil {
+		return game.Tag{}, ErrSyntax
+	}
+	var value strings.Builder
+	for {
+		c, _, err := r.r.ReadRune()
+		if err != nil {
+			return game.Tag{}, ErrSyntax
+		}
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			if c, _, err = r.r.ReadRune(); err != nil {
+				return game.Tag{}, ErrSyntax
+			}
+		}
+		value.WriteRune(c)
+	}
+	if _, err := r.readUntil(']'); err != nil {
+		return game.Tag{}, ErrSyntax
+	}
+	return game.Tag{Name: strings.TrimSpace(name), Value: value.String()}, nil
+}
+
+func isResult(s string) bool {
+	return s == "*" || chess.ParseResult(s) != chess.NoResult
+}
+
+// readMoveText 读取着法部分直到结果记号、下一局的标签或者输入结束
+func (r *Reader) readMoveText() ([]token, string, error) {
+	var tokens []token
+	depth := 0
+	for {
+		if err := r.skipSpace(); err == io.EOF {
+			return tokens, "", nil
+		} else if err != nil {
+			return nil, "", err
+		}
+		c, _ := r.peek()
+		switch c {
+		case '[':
+			// 变着没有结束时遇到下一局的标签，不读取标签，下一次从这里继续
+			if depth == 0 {
+				return tokens, "", nil
+			}
+			return nil, "", errUnterminated
+		case '{':
+			r.r.ReadRune()
+			text, err := r.readComment()
+			if err != nil {
+				return nil, "", ErrSyntax
+			}
+			tokens = append(tokens, token{tokenComment, strings.TrimSpace(text)})
+		case ';':
+			text, _ := r.readUntil('\n')
+			tokens = append(tokens, token{tokenComment, strings.TrimSpace(text[1:])})
+		case '(':
+			r.r.ReadRune()
+			depth++
+			tokens = append(tokens, token{kind: tokenOpen})
+		case ')':
+			r.r.ReadRune()
+			if depth--; depth < 0 {
+				return nil, "", ErrSyntax
+			}
+			tokens = append(tokens, token{kind: tokenClose})
+		default:
+			var sb strings.Builder
+			for {
+				c, _, err := r.r.ReadRune()
+				if err != nil {
+					break
+				}
+				if unicode.IsSpace(c) || strings.ContainsRune("{}();[", c) {
+					r.r.UnreadRune()
+					break
+				}
+				sb.WriteRune(c)
+			}
+			word := sb.String()
+			if depth == 0 && isResult(word) {
+				return tokens, word, nil
+			}
+			if strings.HasPrefix(word, "$") {
+				tokens = append(tokens, token{tokenNAG, word[1:]})
+			} else {
+				tokens = append(tokens, token{tokenWord, word})
+			}
+		}
+	}
+}
+
+// splitMove 去掉记号前面的回合数和后面的 ! ? 符号
+func splitMove(word string) (move string, nag int) {
+	i := 0
+	for i < len(word) && word[i] >= '0' && word[i] <= '9' {
+		i++
+	}
+	if i < len(word) && word[i] == '.' {
+		for i < len(word) && word[i] == '.' {
+			i++
+		}
+		word = word[i:]
+	}
+	move = strings.TrimRight(word, "!?")
+	nag, _ = game.ParseNAG(word[len(move):])
+	return move, nag
+}
+
+// Read 读取下一局棋谱，没有更多对局时返回 io.EOF。某一局有语法错误或者着法不合法时返回错误，
+// 语法错误之后会跳到行首的下一个标签，之后仍然可以继续读取下一局
+func (r *Reader) Read() (*game.Game, error) {
+	if err := r.skipSpace(); err != nil {
+		return nil, err
+	}
+	r.games++
+	g := game.NewGame()
+	for {
+		if err := r.skipSpace(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if c, err := r.peek(); err != nil || c != '[' {
+			break
+		}
+		tag, err := r.readTag()
+		if err != nil {
+			r.skipGame()
+			return nil, fmt.Errorf("pgn: game %d: invalid tag", r.games)
+		}
+		g.Tags = append(g.Tags, tag)
+	}
+	tokens, result, err := r.readMoveText()
+	if err != nil {
+		if err != errUnterminated {
+			r.skipGame()
+		}
+		return nil, fmt.Errorf("pgn: game %d: %v", r.games, err)
+	}
+	if result == "" {
+		result = g.Tag("Result")
+	}
+	g.Result = chess.ParseResult(result)
+	if err := replay(g, tokens); err != nil {
+		return nil, fmt.Errorf("pgn: game %d: %v", r.games, err)
+	}
+	return g, nil
+}
+
+// replay 在棋盘上重放着法记号，生成着法树
+func replay(g *game.Game, tokens []token) error {
+	b, err := g.Board()
+	if err != nil {
+		return err
+	}
+	format := g.Tag("Format")
+	if format != FormatICCS && format != FormatWXF && format != FormatChinese {
+		format = ""
+	}
+	type state struct {
+		node  *game.Node
+		board *chess.Board
+	}
+	var stack []state
+	node := g.Root
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenComment:
+			if node.Comment != "" {
+				node.Comment += " "
+			}
+			node.Comment += t.text
+		case tokenNAG:
+			nag, err := strconv.Atoi(t.text)
+			if err != nil {
+				return fmt.Errorf("invalid NAG $%s", t.text)
+			}
+			node.NAGs = append(node.NAGs, nag)
+		case tokenOpen:
+			// 变着从上一步之前的局面开始
+			if node.Parent == nil {
+				return errors.New("variation before the first move")
+			}
+			stack = append(stack, state{node, b})
+			b = b.Copy()
+			b.Pop()
+			node = node.Parent
+		case tokenClose:
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			node, b = top.node, top.board
+		case tokenWord:
+			text, nag := splitMove(t.text)
+			if text == "" {
+				continue
+			}
+			move, err := parseMove(b, text, format)
+			if err != nil {
+				return err
+			}
+			b.Push(move)
+			node = node.Add(move)
+			if nag != 0 {
+				node.NAGs = append(node.NAGs, nag)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return ErrSyntax
+	}
+	return nil
+}
+
+// ReadAll 读取所有对局，遇到错误时停止
+func ReadAll(r io.Reader) ([]*game.Game, error) {
+	var games []*game.Game
+	reader := NewReader(r)
+	for {
+		g, err := reader.Read()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+		games = append(games, g)
+	}
+}
@@ -0,0 +1,135 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+// 标准的七个标签，写出时按这个顺序排在最前面
+var rosterTags = []string{"Event", "Site", "Date", "Round", "Red", "Black", "Result"}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// writer 把着法树转换为一串记号，括号直接连在相邻的记号上
+type writer struct {
+	format string
+	tokens []string
+	open   bool
+}
+
+func (w *writer) add(token string) {
+	if w.open {
+		token = "(" + token
+		w.open = false
+	}
+	w.tokens = append(w.tokens, token)
+}
+
+func (w *writer) close() {
+	w.tokens[len(w.tokens)-1] += ")"
+}
+
+// move 写出 b 局面下的一步，黑方走子时只有 forceNumber 为 true 才写出回合数
+func (w *writer) move(b *chess.Board, node *game.Node, forceNumber bool) {
+	if b.Turn() == chess.Red {
+		w.add(fmt.Sprintf("%d.", b.FullmoveNumber()))
+	} else if forceNumber {
+		w.add(fmt.Sprintf("%d...", b.FullmoveNumber()))
+	}
+	w.add(formatMove(b, node.Move, w.format))
+	w.annotations(node)
+}
+
+// commentEscaper 转义注释中的 } 和 \，读取时还原
+var commentEscaper = strings.NewReplacer(`\`, `\\`, "}", `\}`)
+
+func (w *writer) annotations(node *game.Node) {
+	for _, nag := range node.NAGs {
+		w.add(fmt.Sprintf("$%d", nag))
+	}
+	if node.Comment != "" {
+		w.add("{" + commentEscaper.Replace(node.Comment) + "}")
+	}
+}
+
+// line 写出 node 之后的主线和其中的变着，b 是 node 的局面，会被修改
+func (w *writer) line(b *chess.Board, node *game.Node, forceNumber bool) {
+	for len(node.Children) > 0 {
+		main := node.Children[0]
+		w.move(b, main, forceNumber)
+		for _, variation := range node.Children[1:] {
+			w.open = true
+			w.move(b, variation, true)
+			vb := b.Copy()
+			vb.Push(variation.Move)
+			w.line(vb, variation, variation.Comment != "" || len(variation.NAGs) > 0)
+			w.close()
+		}
+		forceNumber = len(node.Children) > 1 || main.Comment != "" || len(main.NAGs) > 0
+		b.Push(main.Move)
+		node = main
+	}
+}
+
+// Write 写出 PGN 棋谱，着法使用 Format 标签指定的格式，没有指定时使用 ICCS 坐标。
+// 着法部分每行不超过 80 个字符
+func Write(w io.Writer, g *game.Game) error {
+	bw := bufio.NewWriter(w)
+	format := g.Tag("Format")
+	if format != FormatWXF && format != FormatChinese {
+		format = FormatICCS
+	}
+	written := map[string]bool{"Format": true}
+	for _, name := range rosterTags {
+		value := g.Tag(name)
+		if name == "Result" {
+			value = g.Result.String()
+		} else if value == "" {
+			value = "?"
+		}
+		fmt.Fprintf(bw, "[%s \"%s\"]\n", name, escape(value))
+		written[name] = true
+	}
+	fmt.Fprintf(bw, "[Format \"%s\"]\n", format)
+	for _, tag := range g.Tags {
+		if !written[tag.Name] {
+			fmt.Fprintf(bw, "[%s \"%s\"]\n", tag.Name, escape(tag.Value))
+		}
+	}
+	fmt.Fprintln(bw)
+
+	b, err := g.Board()
+	if err != nil {
+		return err
+	}
+	tw := &writer{format: format}
+	root := g.Root
+	if root == nil {
+		root = &game.Node{}
+	}
+	tw.annotations(root)
+	tw.line(b, root, true)
+	tw.add(g.Result.String())
+	lineLength := 0
+	for _, token := range tw.tokens {
+		if lineLength > 0 && lineLength+1+len(token) > 80 {
+			fmt.Fprintln(bw)
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			bw.WriteByte(' ')
+			lineLength++
+		}
+		bw.WriteString(token)
+		lineLength += len(token)
+	}
+	fmt.Fprint(bw, "\n\n")
+	return bw.Flush()
+}
@@ -0,0 +1,243 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+func runSession(t *testing.T, script string) []string {
+	out := &bytes.Buffer{}
+	session := NewSession(engine.NewEngine(), out)
+	if err := session.Run(strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func lastLine(lines []string, prefix string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], prefix) {
+			return lines[i]
+		}
+	}
+	return ""
+}
+
+func TestUcciHandshake(t *testing.T) {
+	lines := runSession(t, "ucci\nisready\nquit\n")
+	if lines[0] != "id name "+EngineName {
+		t.Errorf("unexpected first line %q", lines[0])
+	}
+	for _, expected := range []string{"option hashsize type spin min 1 max 1024 default 16", "ucciok", "readyok", "bye"} {
+		if lastLine(lines, expected) == "" {
+			t.Errorf("missing %q in %q", expected, lines)
+		}
+	}
+}
+
+func TestUcciMateInOne(t *testing.T) {
+	lines := runSession(t, "ucci\nsetoption hashsize 8\nposition fen 4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1\ngo depth 3\n")
+	if line := lastLine(lines, "bestmove"); line != "bestmove a0a9" {
+		t.Errorf("got %q, want bestmove a0a9", line)
+	}
+}
+
+func TestUcciPositionMoves(t *testing.T) {
+	lines := runSession(t, "ucci\nposition startpos moves h2e2 h9g7\ngo depth 2\nquit\n")
+	fields := strings.Fields(lastLine(lines, "bestmove"))
+	if len(fields) < 2 {
+		t.Fatalf("no bestmove in %q", lines)
+	}
+	b := chess.NewBoard()
+	for _, text := range []string{"h2e2", "h9g7", fields[1]} {
+		move, err := chess.ParseMove(text)
+		if err != nil || !b.IsLegal(move) {
+			t.Fatalf("illegal move %s", text)
+		}
+		b.Push(move)
+	}
+}
+
+func TestUcciStop(t *testing.T) {
+	lines := runSession(t, "ucci\nposition startpos\ngo depth infinite\nstop\n")
+	if lastLine(lines, "bestmove") == "" {
+		t.Errorf("no bestmove after stop in %q", lines)
+	}
+}
+
+func TestUcciNoBestMove(t *testing.T) {
+	lines := runSession(t, "position fen R3k4/1R7/9/9/9/9/9/9/9/3K5 b - - 0 1\ngo depth 2\n")
+	if lastLine(lines, "nobestmove") == "" {
+		t.Errorf("expected nobestmove in %q", lines)
+	}
+}
+
+func TestUciHandshake(t *testing.T) {
+	lines := runSession(t, "uci\nisready\nquit\n")
+	for _, expected := range []string{"option name Hash type spin default 16 min 1 max 1024", "uciok", "readyok"} {
+		if lastLine(lines, expected) == "" {
+			t.Errorf("missing %q in %q", expected, lines)
+		}
+	}
+	if lastLine(lines, "bye") != "" {
+		t.Error("uci session should not answer quit")
+	}
+}
+
+func TestUciMultiPV(t *testing.T) {
+	lines := runSession(t, "uci\nsetoption name MultiPV value 3\nucinewgame\nposition startpos\ngo depth 2\n")
+	seen := map[string]bool{}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "info depth 2 ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "pv" && i+1 < len(fields) {
+				seen[fields[i+1]] = true
+			}
+		}
+		if !strings.Contains(line, " score cp ") || !strings.Contains(line, " nps ") {
+			t.Errorf("malformed info line %q", line)
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected 3 distinct pv lines at depth 2, got %v", seen)
+	}
+	if lastLine(lines, "bestmove") == "" {
+		t.Error("missing bestmove")
+	}
+}
+
+func TestUciMateScore(t *testing.T) {
+	lines := runSession(t, "uci\nposition fen 4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1\ngo wtime 10000 btime 10000 depth 3\n")
+	if !strings.Contains(lastLine(lines, "info depth"), "score mate 1") {
+		t.Errorf("expected mate score in %q", lines)
+	}
+	if line := lastLine(lines, "bestmove"); line != "bestmove a0a9" {
+		t.Errorf("got %q, want bestmove a0a9", line)
+	}
+}
+
+// syncBuffer 允许在会话输出的同时读取已经输出的内容
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// 无限搜索和后台思考即使提前结束，也要等到 stop 或 ponderhit 才能输出 bestmove
+func TestHeldBestMove(t *testing.T) {
+	tests := []struct {
+		script  string
+		release string
+	}{
+		{"uci\nposition fen 4k4/1R7/9/9/9/9/9/9/9/R2K5 w - - 0 1\ngo infinite\n", "stop\n"},
+		{"uci\nposition startpos\ngo ponder depth 1\n", "ponderhit\n"},
+		{"ucci\nposition startpos moves h2e2\ngo ponder time 60000 depth 1\n", "ponderhit\n"},
+	}
+	for _, test := range tests {
+		out := &syncBuffer{}
+		in, w := io.Pipe()
+		session := NewSession(engine.NewEngine(), out)
+		done := make(chan error)
+		go func() {
+			done <- session.Run(in)
+		}()
+		io.WriteString(w, test.script)
+		time.Sleep(300 * time.Millisecond)
+		if strings.Contains(out.String(), "bestmove") {
+			t.Errorf("%q: bestmove sent before %q", test.script, test.release)
+		}
+		io.WriteString(w, test.release)
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), "bestmove") && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !strings.Contains(out.String(), "bestmove") {
+			t.Errorf("%q: no bestmove after %q", test.script, test.release)
+		}
+		w.Close()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBench(t *testing.T) {
+	lines := runSession(t, "bench 2\nquit\n")
+	if n := len(lines); n != len(engine.BenchFens)+2 {
+		t.Fatalf("got %d lines: %q", n, lines)
+	}
+	if line := lastLine(lines, "info string bench depth 2 nodes "); line == "" {
+		t.Errorf("missing bench summary in %q", lines)
+	}
+}
+
+// 搜索进行时 isready 要立即回答，否则读不到结束搜索的 stop 和 ponderhit
+func TestReadyDuringSearch(t *testing.T) {
+	for _, test := range []struct {
+		script  string
+		release string
+	}{
+		{"uci\nposition startpos\ngo ponder wtime 10000 btime 10000\n", "ponderhit\n"},
+		{"uci\nposition startpos\ngo infinite\n", "stop\n"},
+	} {
+		out := &syncBuffer{}
+		in, w := io.Pipe()
+		session := NewSession(engine.NewEngine(), out)
+		done := make(chan error)
+		go func() {
+			done <- session.Run(in)
+		}()
+		io.WriteString(w, test.script+"isready\n")
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), "readyok") && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !strings.Contains(out.String(), "readyok") {
+			t.Fatalf("%q: no readyok during the search", test.script)
+		}
+		io.WriteString(w, test.release+"quit\n")
+		w.Close()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%q: session did not finish after %q", test.script, test.release)
+		}
+		if !strings.Contains(out.String(), "bestmove") {
+			t.Errorf("%q: no bestmove in %q", test.script, out.String())
+		}
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	lines := runSession(t, "position startpos moves h2e2\nd\nquit\n")
+	if n := len(lines); n != 14 || lines[0] != "9 r n b a k a b n r" || lines[8] != "2 . C . + C + . . ." {
+		t.Fatalf("got %d lines: %q", n, lines)
+	}
+	if lines[12] != "rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C2C4/9/RNBAKABNR b - - 1 1" {
+		t.Errorf("fen line %q", lines[12])
+	}
+}
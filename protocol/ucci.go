@@ -0,0 +1,320 @@
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+const (
+	EngineName   = "GoChess"
+	EngineAuthor = "clysto"
+)
+
+// UCCI 协议中的选项名和引擎选项名的对应关系
+var ucciOptionNames = map[string]string{
+	"Hash":     "hashsize",
+	"OwnBook":  "usebook",
+	"BookFile": "bookfiles",
+}
+
+// Session 负责一次和界面程序的会话，从输入读取命令，把结果写到输出
+type Session struct {
+	engine *engine.Engine
+	board  *chess.Board
+
+	mu  sync.Mutex
+	out io.Writer
+	wg  sync.WaitGroup
+	// release 不为 nil 时无限搜索或者后台思考的结果要等到 stop 或 ponderhit 之后才能输出
+	release chan struct{}
+	// infinite 表示当前的搜索是无限搜索，ponderhit 之后仍然要等待 stop
+	infinite bool
+	// uci 为 true 时使用 UCI 协议，否则使用 UCCI 协议
+	uci bool
+}
+
+func NewSession(e *engine.Engine, out io.Writer) *Session {
+	s := &Session{
+		engine: e,
+		board:  chess.NewBoard(),
+		out:    out,
+	}
+	e.OnInfo = s.sendInfo
+	return s
+}
+
+func (s *Session) send(format string, a ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", a...)
+}
+
+// Run 处理命令直到收到 quit 或者输入结束
+func (s *Session) Run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if !s.handle(fields[0], fields[1:]) {
+			return nil
+		}
+	}
+	// 输入结束时等待有限制的搜索完成，无限搜索和后台思考直接停止
+	if s.release != nil {
+		s.stop()
+	}
+	s.wg.Wait()
+	return scanner.Err()
+}
+
+func (s *Session) handle(command string, args []string) bool {
+	switch command {
+	case "ucci":
+		s.send("id name %s", EngineName)
+		s.send("id author %s", EngineAuthor)
+		for _, option := range engine.OptionList {
+			name := ucciOptionNames[option.Name]
+			if name == "" {
+				name = strings.ToLower(option.Name)
+			}
+			if option.Type == "spin" {
+				s.send("option %s type spin min %d max %d default %s", name, option.Min, option.Max, option.Default)
+			} else {
+				s.send("option %s type %s default %s", name, option.Type, option.Default)
+			}
+		}
+		s.send("ucciok")
+	case "uci":
+		s.uci = true
+		s.uciHandshake()
+	case "isready":
+		// 不能等待正在进行的搜索，无限搜索和后台思考要等读到 stop 或 ponderhit 才会结束
+		s.send("readyok")
+	case "setoption":
+		s.stop()
+		if s.uci {
+			s.setOptionUCI(args)
+		} else {
+			s.setOption(args)
+		}
+	case "ucinewgame":
+		s.stop()
+		s.engine.NewGame()
+	case "position":
+		s.stop()
+		s.position(args)
+	case "go":
+		s.stop()
+		s.goSearch(args)
+	case "ponderhit":
+		// UCCI 的 ponderhit draw 表示对方提和，这里不接受提和，当作普通的 ponderhit 处理
+		s.engine.PonderHit()
+		if !s.infinite {
+			s.releaseResult()
+		}
+	case "stop":
+		s.stop()
+	case "bench":
+		s.stop()
+		s.bench(args)
+	case "d":
+		// 调试用，输出当前局面
+		for _, line := range strings.Split(s.board.String(), "\n") {
+			s.send("%s", line)
+		}
+	case "quit":
+		s.stop()
+		if !s.uci {
+			s.send("bye")
+		}
+		return false
+	default:
+		s.send("info string unknown command %s", command)
+	}
+	return true
+}
+
+func (s *Session) stop() {
+	s.engine.Stop()
+	s.releaseResult()
+	s.wg.Wait()
+}
+
+func (s *Session) releaseResult() {
+	if s.release != nil {
+		close(s.release)
+		s.release = nil
+	}
+}
+
+func (s *Session) setOption(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	name, value := args[0], strings.Join(args[1:], " ")
+	for option, ucciName := range ucciOptionNames {
+		if strings.EqualFold(name, ucciName) {
+			name = option
+		}
+	}
+	if err := s.engine.SetOption(name, value); err != nil {
+		s.send("info string %v", err)
+	}
+}
+
+// bench 用新的引擎实例搜索固定的局面，输出每个局面和总的节点数，不影响当前的引擎
+func (s *Session) bench(args []string) {
+	depth := engine.BenchDepth
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+			depth = n
+		}
+	}
+	result := engine.Bench(depth)
+	for _, position := range result.Positions {
+		s.send("info string bench %s bestmove %s score %d nodes %d", position.Fen, position.BestMove, position.Score, position.Stats.Nodes)
+	}
+	s.send("info string bench depth %d nodes %d time %d nps %d", depth, result.Nodes, result.Time.Milliseconds(), result.NPS)
+}
+
+func (s *Session) position(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	var fen string
+	i := 1
+	switch args[0] {
+	case "startpos":
+		fen = chess.StartingFen
+	case "fen":
+		for ; i < len(args) && args[i] != "moves"; i++ {
+		}
+		fen = strings.Join(args[1:i], " ")
+	default:
+		s.send("info string invalid position command")
+		return
+	}
+	b, err := chess.NewBoardFromFen(fen)
+	if err != nil {
+		s.send("info string %v", err)
+		return
+	}
+	if i < len(args) && args[i] == "moves" {
+		for _, text := range args[i+1:] {
+			move, err := chess.ParseMove(text)
+			if err != nil || !b.IsLegal(move) {
+				s.send("info string illegal move %s", text)
+				return
+			}
+			b.Push(move)
+		}
+	}
+	s.board = b
+}
+
+func parseLimits(args []string) engine.Limits {
+	limits := engine.Limits{}
+	var remaining, increment time.Duration
+	movesToGo := 0
+	for i := 0; i < len(args); i++ {
+		value := 0
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+		switch args[i] {
+		case "depth":
+			if i+1 < len(args) && args[i+1] == "infinite" {
+				limits.Infinite = true
+			} else {
+				limits.Depth = value
+			}
+			i++
+		case "nodes":
+			limits.Nodes = uint64(value)
+			i++
+		case "time":
+			remaining = time.Duration(value) * time.Millisecond
+			i++
+		case "increment":
+			increment = time.Duration(value) * time.Millisecond
+			i++
+		case "movestogo":
+			movesToGo = value
+			i++
+		case "opptime", "oppincrement", "oppmovestogo":
+			i++
+		case "infinite":
+			limits.Infinite = true
+		case "ponder":
+			limits.Ponder = true
+		}
+	}
+	if remaining > 0 {
+		limits.MoveTime = engine.TimeForMove(remaining, increment, movesToGo)
+	}
+	return limits
+}
+
+func (s *Session) goSearch(args []string) {
+	var limits engine.Limits
+	if s.uci {
+		limits = parseLimitsUCI(args, s.board.Turn())
+	} else {
+		limits = parseLimits(args)
+	}
+	s.infinite = limits.Infinite
+	var release chan struct{}
+	if limits.Infinite || limits.Ponder {
+		release = make(chan struct{})
+	}
+	s.release = release
+	results := s.engine.Go(s.board, limits)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		result := <-results
+		if release != nil {
+			<-release
+		}
+		s.sendBestMove(result)
+	}()
+}
+
+func formatPV(pv []*chess.Move) string {
+	moves := make([]string, len(pv))
+	for i, move := range pv {
+		moves[i] = move.String()
+	}
+	return strings.Join(moves, " ")
+}
+
+func (s *Session) sendInfo(info *engine.Info) {
+	if s.uci {
+		s.sendInfoUCI(info)
+		return
+	}
+	s.send("info depth %d score %d time %d nodes %d pv %s",
+		info.Depth, info.Score, info.Time.Milliseconds(), info.Nodes, formatPV(info.PV))
+}
+
+func (s *Session) sendBestMove(result engine.Result) {
+	if result.BestMove == nil && s.uci {
+		s.send("bestmove (none)")
+	} else if result.BestMove == nil {
+		s.send("nobestmove")
+	} else if result.PonderMove != nil {
+		s.send("bestmove %s ponder %s", result.BestMove, result.PonderMove)
+	} else {
+		s.send("bestmove %s", result.BestMove)
+	}
+}
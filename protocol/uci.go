@@ -0,0 +1,108 @@
+package protocol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+func (s *Session) uciHandshake() {
+	s.send("id name %s", EngineName)
+	s.send("id author %s", EngineAuthor)
+	for _, option := range engine.OptionList {
+		switch option.Type {
+		case "spin":
+			s.send("option name %s type spin default %s min %d max %d", option.Name, option.Default, option.Min, option.Max)
+		default:
+			s.send("option name %s type %s default %s", option.Name, option.Type, option.Default)
+		}
+	}
+	s.send("uciok")
+}
+
+// setOptionUCI 解析 setoption name <name> [value <value>]，选项名可以包含空格
+func (s *Session) setOptionUCI(args []string) {
+	var name, value []string
+	current := &name
+	for _, arg := range args {
+		switch arg {
+		case "name":
+			current = &name
+		case "value":
+			current = &value
+		default:
+			*current = append(*current, arg)
+		}
+	}
+	if err := s.engine.SetOption(strings.Join(name, " "), strings.Join(value, " ")); err != nil {
+		s.send("info string %v", err)
+	}
+}
+
+func parseLimitsUCI(args []string, turn bool) engine.Limits {
+	limits := engine.Limits{}
+	var times, increments [2]time.Duration
+	movesToGo := 0
+	for i := 0; i < len(args); i++ {
+		value := 0
+		if i+1 < len(args) {
+			value, _ = strconv.Atoi(args[i+1])
+		}
+		ms := time.Duration(value) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			limits.Depth = value
+			i++
+		case "nodes":
+			limits.Nodes = uint64(value)
+			i++
+		case "movetime":
+			limits.MoveTime = ms
+			i++
+		case "wtime":
+			times[0] = ms
+			i++
+		case "btime":
+			times[1] = ms
+			i++
+		case "winc":
+			increments[0] = ms
+			i++
+		case "binc":
+			increments[1] = ms
+			i++
+		case "movestogo":
+			movesToGo = value
+			i++
+		case "infinite":
+			limits.Infinite = true
+		case "ponder":
+			limits.Ponder = true
+		}
+	}
+	side := 0
+	if turn == chess.Black {
+		side = 1
+	}
+	if limits.MoveTime == 0 && times[side] > 0 {
+		limits.MoveTime = engine.TimeForMove(times[side], increments[side], movesToGo)
+	}
+	return limits
+}
+
+func formatScoreUCI(info *engine.Info) string {
+	if mate := info.Mate(); mate != 0 {
+		return fmt.Sprintf("mate %d", mate)
+	}
+	return fmt.Sprintf("cp %d", info.Score)
+}
+
+func (s *Session) sendInfoUCI(info *engine.Info) {
+	s.send("info depth %d seldepth %d multipv %d score %s nodes %d nps %d time %d pv %s",
+		info.Depth, info.SelDepth, info.MultiPV, formatScoreUCI(info), info.Nodes, info.NPS(),
+		info.Time.Milliseconds(), formatPV(info.PV))
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+// 动画每一帧默认的停留时间
+const (
+	DefaultDelay     = time.Second
+	DefaultLastDelay = 3 * time.Second
+)
+
+type GIFOptions struct {
+	// Options 中的 Highlights 和 Arrows 会被忽略，每一帧标出上一步的起点和终点
+	Options
+	// Delay 是每一步停留的时间，LastDelay 是最后一帧停留的时间，为 0 时使用默认值
+	Delay     time.Duration
+	LastDelay time.Duration
+}
+
+func centiseconds(d time.Duration, fallback time.Duration) int {
+	if d <= 0 {
+		d = fallback
+	}
+	return int(d / (10 * time.Millisecond))
+}
+
+// frame 画出一帧并转换为 GIF 使用的调色板图片
+func frame(b *chess.Board, opts Options, last *chess.Move) *image.Paletted {
+	opts.Arrows = nil
+	opts.Highlights = nil
+	if last != nil {
+		opts.Highlights = []uint8{last.FromSquare, last.ToSquare}
+	}
+	img := Image(b, &opts)
+	paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+	return paletted
+}
+
+// GIF 把棋谱的主线画成 GIF 动画写到 w，第一帧是起始局面，之后每一步一帧
+func GIF(w io.Writer, g *game.Game, opts *GIFOptions) error {
+	if opts == nil {
+		opts = &GIFOptions{}
+	}
+	b, err := g.Board()
+	if err != nil {
+		return err
+	}
+	delay := centiseconds(opts.Delay, DefaultDelay)
+	anim := &gif.GIF{}
+	add := func(last *chess.Move) {
+		anim.Image = append(anim.Image, frame(b, opts.Options, last))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	add(nil)
+	for _, move := range g.MainLine() {
+		b.Push(move)
+		add(move)
+	}
+	anim.Delay[len(anim.Delay)-1] = centiseconds(opts.LastDelay, DefaultLastDelay)
+	return gif.EncodeAll(w, anim)
+}
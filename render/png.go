@@ -0,0 +1,110 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/resources/assets"
+	"github.com/fogleman/gg"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+var (
+	labelFace     font.Face
+	labelFaceOnce sync.Once
+)
+
+// coordinateFace 返回画坐标用的字体，坐标只有字母和数字，使用 Go 自带的字体
+func coordinateFace() font.Face {
+	labelFaceOnce.Do(func() {
+		tt, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			panic(err)
+		}
+		labelFace, err = opentype.NewFace(tt, &opentype.FaceOptions{Size: 48, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			panic(err)
+		}
+	})
+	return labelFace
+}
+
+// drawTile 把 piece.png 中的一个图片画在格子上
+func drawTile(dst *image.RGBA, l layout, sq uint8, tile image.Rectangle) {
+	x, y := l.center(sq)
+	min := image.Pt(int(math.Round(x))-assets.PieceSize/2, int(math.Round(y))-assets.PieceSize/2)
+	draw.Draw(dst, image.Rectangle{min, min.Add(tile.Size())}, assets.Pieces, tile.Min, draw.Over)
+}
+
+// Image 画出局面 b，opts 为 nil 时使用默认设置
+func Image(b *chess.Board, opts *Options) *image.RGBA {
+	if opts == nil {
+		opts = &Options{}
+	}
+	l := newLayout(opts)
+	w, h := l.size()
+	img := image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := image.Pt(int(l.margin), 0)
+	draw.Draw(img, assets.Background.Bounds().Add(offset), assets.Background, image.Point{}, draw.Over)
+	draw.Draw(img, assets.Board.Bounds().Add(offset), assets.Board, image.Point{}, draw.Over)
+
+	for _, sq := range opts.Highlights {
+		drawTile(img, l, sq, assets.Tile(assets.BlueBox))
+	}
+	for _, sq := range chess.ScanReversed(b.Occupied()) {
+		drawTile(img, l, sq, assets.PieceTile(b.PieceAt(sq)))
+	}
+
+	dc := gg.NewContextForRGBA(img)
+	for _, arrow := range opts.Arrows {
+		x0, y0 := l.center(arrow.From)
+		x1, y1 := l.center(arrow.To)
+		shaft, head := arrowShape(x0, y0, x1, y1)
+		dc.SetColor(arrowColor(arrow))
+		dc.SetLineWidth(24)
+		dc.SetLineCap(gg.LineCapButt)
+		dc.DrawLine(shaft[0], shaft[1], shaft[2], shaft[3])
+		dc.Stroke()
+		dc.MoveTo(head[0], head[1])
+		dc.LineTo(head[2], head[3])
+		dc.LineTo(head[4], head[5])
+		dc.ClosePath()
+		dc.Fill()
+	}
+	if opts.Coordinates {
+		dc.SetFontFace(coordinateFace())
+		dc.SetColor(color.Black)
+		files, ranks := l.labels()
+		for i, s := range files {
+			x, _ := l.point(float64(i), 0)
+			dc.DrawStringAnchored(s, x, boardHeight+l.margin/2, 0.5, 0.35)
+		}
+		for i, s := range ranks {
+			_, y := l.point(0, float64(i))
+			dc.DrawStringAnchored(s, l.margin/2, y, 0.5, 0.35)
+		}
+	}
+
+	if opts.Width <= 0 || opts.Width == img.Bounds().Dx() {
+		return img
+	}
+	height := int(math.Round(h * float64(opts.Width) / w))
+	scaled := image.NewRGBA(image.Rect(0, 0, opts.Width, height))
+	xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return scaled
+}
+
+// PNG 把局面 b 画成 PNG 图片写到 w
+func PNG(w io.Writer, b *chess.Board, opts *Options) error {
+	return png.Encode(w, Image(b, opts))
+}
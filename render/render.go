@@ -0,0 +1,129 @@
+// Package render 把棋盘画成 PNG 或者 SVG 图片，不需要打开窗口，可以用来生成文章和测试报告中的棋图。
+// PNG 使用 resources/assets 中的棋盘和棋子图片，SVG 是矢量绘制的，棋子上的字使用马善政字体。
+package render
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 棋图的坐标以棋盘图片的像素为单位
+const (
+	boardWidth  = 1520
+	boardHeight = 1680
+	gridOrigin  = 120
+	gridSize    = 160
+	// coordinateMargin 是显示坐标时在左边和下边增加的宽度
+	coordinateMargin = 80
+)
+
+// DefaultArrowColor 是没有指定颜色时箭头的颜色
+var DefaultArrowColor = color.NRGBA{R: 0, G: 150, B: 60, A: 180}
+
+// Arrow 是从一个格子指向另一个格子的箭头
+type Arrow struct {
+	From  uint8
+	To    uint8
+	Color color.Color
+}
+
+type Options struct {
+	// Width 是输出图片的宽度，0 表示使用原图的大小，高度按比例计算
+	Width int
+	// Flip 为 true 时黑方在下
+	Flip bool
+	// Coordinates 为 true 时在棋盘的左边和下边显示 ICCS 坐标
+	Coordinates bool
+	// Highlights 是用方框标出的格子，例如上一步的起点和终点
+	Highlights []uint8
+	Arrows     []Arrow
+}
+
+// layout 计算格子在棋图中的位置
+type layout struct {
+	flip   bool
+	margin float64
+}
+
+func newLayout(opts *Options) layout {
+	l := layout{flip: opts.Flip}
+	if opts.Coordinates {
+		l.margin = coordinateMargin
+	}
+	return l
+}
+
+func (l layout) size() (float64, float64) {
+	return boardWidth + l.margin, boardHeight + l.margin
+}
+
+// point 返回第 col 列、第 row 行交叉点的位置，行列从左上角开始计算
+func (l layout) point(col float64, row float64) (float64, float64) {
+	return l.margin + gridOrigin + gridSize*col, gridOrigin + gridSize*row
+}
+
+// cell 返回格子显示在第几列第几行
+func (l layout) cell(sq uint8) (int, int) {
+	col, row := chess.SquareFile(sq)-3, 12-chess.SquareRank(sq)
+	if l.flip {
+		col, row = 8-col, 9-row
+	}
+	return col, row
+}
+
+func (l layout) center(sq uint8) (float64, float64) {
+	col, row := l.cell(sq)
+	return l.point(float64(col), float64(row))
+}
+
+// labels 返回显示在每一列下面和每一行左边的坐标
+func (l layout) labels() (files [9]string, ranks [10]string) {
+	for i := range files {
+		file := i
+		if l.flip {
+			file = 8 - i
+		}
+		files[i] = string(rune('a' + file))
+	}
+	for i := range ranks {
+		rank := 9 - i
+		if l.flip {
+			rank = i
+		}
+		ranks[i] = string(rune('0' + rank))
+	}
+	return files, ranks
+}
+
+// arrowShape 返回箭头的起点、箭杆的终点和箭头三角形的顶点，箭头两端离开格子中心一段距离
+func arrowShape(x0, y0, x1, y1 float64) (shaft [4]float64, head [6]float64) {
+	dx, dy := x1-x0, y1-y0
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+	const (
+		inset      = 40
+		headLength = 70
+		headWidth  = 45
+	)
+	tipX, tipY := x1-ux*inset, y1-uy*inset
+	baseX, baseY := tipX-ux*headLength, tipY-uy*headLength
+	shaft = [4]float64{x0 + ux*inset, y0 + uy*inset, baseX, baseY}
+	head = [6]float64{
+		tipX, tipY,
+		baseX - uy*headWidth, baseY + ux*headWidth,
+		baseX + uy*headWidth, baseY - ux*headWidth,
+	}
+	return shaft, head
+}
+
+func arrowColor(a Arrow) color.Color {
+	if a.Color == nil {
+		return DefaultArrowColor
+	}
+	return a.Color
+}
@@ -0,0 +1,99 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/gif"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+)
+
+func TestPNG(t *testing.T) {
+	b := chess.NewBoard()
+	img := Image(b, nil)
+	if img.Bounds().Dx() != boardWidth || img.Bounds().Dy() != boardHeight {
+		t.Fatalf("size %v", img.Bounds())
+	}
+	// 有棋子的格子中心和空的交叉点颜色不同
+	empty := img.At(int(gridOrigin+gridSize*4), int(gridOrigin+gridSize*4.5))
+	x, y := newLayout(&Options{}).center(chess.Square(4, 0))
+	if img.At(int(x), int(y)) == empty {
+		t.Error("king was not drawn")
+	}
+
+	buf := &bytes.Buffer{}
+	opts := &Options{Width: 380, Coordinates: true, Flip: true, Highlights: []uint8{chess.Square(4, 0)}}
+	if err := PNG(buf, b, opts); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := decoded.Bounds().Dx(), decoded.Bounds().Dy(); w != 380 || h != 418 {
+		t.Errorf("scaled size %dx%d", w, h)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	b, err := chess.NewBoardFromFen("4k4/9/9/9/9/9/9/9/4A4/3AK4 w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	opts := &Options{
+		Coordinates: true,
+		Arrows:      []Arrow{{From: chess.Square(4, 0), To: chess.Square(4, 1)}},
+		Highlights:  []uint8{chess.Square(4, 9)},
+	}
+	if err := SVG(buf, b, opts); err != nil {
+		t.Fatal(err)
+	}
+	text := buf.String()
+	for _, want := range []string{"帥", "仕", "將", "<polygon", ">a</text>", ">9</text>"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if n := strings.Count(text, "<circle"); n != 8 {
+		t.Errorf("%d circles, want 8", n)
+	}
+	decoder := xml.NewDecoder(buf)
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid svg: %v", err)
+		}
+	}
+}
+
+func TestGIF(t *testing.T) {
+	g := game.NewGame()
+	node := g.Root
+	for _, s := range []string{"h2e2", "h9g7"} {
+		move, _ := chess.ParseMove(s)
+		node = node.Add(move)
+	}
+	node.Parent.Add(&chess.Move{FromSquare: chess.Square(1, 7), ToSquare: chess.Square(4, 7)})
+	buf := &bytes.Buffer{}
+	if err := GIF(buf, g, &GIFOptions{Options: Options{Width: 200}, Delay: 500 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 3 || anim.Delay[0] != 50 || anim.Delay[2] != 300 {
+		t.Errorf("%d frames, delays %v", len(anim.Image), anim.Delay)
+	}
+	if anim.Image[0].Bounds().Dx() != 200 {
+		t.Errorf("frame size %v", anim.Image[0].Bounds())
+	}
+}
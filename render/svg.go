@@ -0,0 +1,129 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 棋子上的字，红方和黑方的车马使用相同的字
+var pieceGlyphs = map[bool][8]string{
+	chess.Red:   {"", "兵", "炮", "車", "馬", "相", "仕", "帥"},
+	chess.Black: {"", "卒", "砲", "車", "馬", "象", "士", "將"},
+}
+
+const (
+	svgFont       = "MaShanZheng, 'Ma Shan Zheng', KaiTi, STKaiti, serif"
+	svgBackground = "#f0d9a8"
+	svgLine       = "#5a3a1a"
+	svgPieceFill  = "#f8e8c4"
+	svgHighlight  = "#1f6fd6"
+	svgRed        = "#c0231e"
+	svgBlack      = "#1a1a1a"
+)
+
+func svgColor(c color.Color) (string, float64) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B), float64(n.A) / 255
+}
+
+// SVG 把局面 b 画成 SVG 图片写到 w，opts 为 nil 时使用默认设置
+func SVG(w io.Writer, b *chess.Board, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	l := newLayout(opts)
+	width, height := l.size()
+	outWidth, outHeight := width, height
+	if opts.Width > 0 {
+		outWidth, outHeight = float64(opts.Width), height*float64(opts.Width)/width
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n",
+		outWidth, outHeight, width, height)
+	fmt.Fprintf(bw, `<rect width="%g" height="%g" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(bw, `<rect x="%g" width="%d" height="%d" fill="%s"/>`+"\n", l.margin, boardWidth, boardHeight, svgBackground)
+
+	line := func(col0, row0, col1, row1 float64) {
+		x0, y0 := l.point(col0, row0)
+		x1, y1 := l.point(col1, row1)
+		fmt.Fprintf(bw, `<line x1="%g" y1="%g" x2="%g" y2="%g"/>`+"\n", x0, y0, x1, y1)
+	}
+	fmt.Fprintf(bw, `<g stroke="%s" stroke-width="4" stroke-linecap="square">`+"\n", svgLine)
+	x0, y0 := l.point(-0.15, -0.15)
+	fmt.Fprintf(bw, `<rect x="%g" y="%g" width="%g" height="%g" fill="none" stroke-width="10"/>`+"\n",
+		x0, y0, gridSize*8.3, gridSize*9.3)
+	for row := 0.0; row <= 9; row++ {
+		line(0, row, 8, row)
+	}
+	for col := 0.0; col <= 8; col++ {
+		if col == 0 || col == 8 {
+			line(col, 0, col, 9)
+		} else {
+			// 竖线在河界处断开
+			line(col, 0, col, 4)
+			line(col, 5, col, 9)
+		}
+	}
+	// 九宫的斜线
+	line(3, 0, 5, 2)
+	line(5, 0, 3, 2)
+	line(3, 7, 5, 9)
+	line(5, 7, 3, 9)
+	fmt.Fprintln(bw, `</g>`)
+	for i, s := range []string{"楚", "河", "漢", "界"} {
+		col := []float64{1.5, 2.5, 5.5, 6.5}[i]
+		x, y := l.point(col, 4.5)
+		fmt.Fprintf(bw, `<text x="%g" y="%g" font-family="%s" font-size="100" fill="%s" text-anchor="middle" dominant-baseline="central">%s</text>`+"\n",
+			x, y, svgFont, svgLine, s)
+	}
+
+	for _, sq := range opts.Highlights {
+		x, y := l.center(sq)
+		fmt.Fprintf(bw, `<rect x="%g" y="%g" width="150" height="150" fill="none" stroke="%s" stroke-width="8"/>`+"\n",
+			x-75, y-75, svgHighlight)
+	}
+	for _, sq := range chess.ScanReversed(b.Occupied()) {
+		piece := b.PieceAt(sq)
+		x, y := l.center(sq)
+		c := svgBlack
+		if piece.Color == chess.Red {
+			c = svgRed
+		}
+		fmt.Fprintf(bw, `<g stroke="%s" stroke-width="5">`, c)
+		fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="72" fill="%s"/>`, x, y, svgPieceFill)
+		fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="60" fill="none" stroke-width="3"/>`, x, y)
+		fmt.Fprintf(bw, `<text x="%g" y="%g" font-family="%s" font-size="90" fill="%s" stroke="none" text-anchor="middle" dominant-baseline="central">%s</text>`,
+			x, y, svgFont, c, pieceGlyphs[piece.Color][piece.PieceType])
+		fmt.Fprintln(bw, `</g>`)
+	}
+
+	for _, arrow := range opts.Arrows {
+		x0, y0 := l.center(arrow.From)
+		x1, y1 := l.center(arrow.To)
+		shaft, head := arrowShape(x0, y0, x1, y1)
+		c, opacity := svgColor(arrowColor(arrow))
+		fmt.Fprintf(bw, `<g fill="%s" stroke="%s" opacity="%.2f">`, c, c, opacity)
+		fmt.Fprintf(bw, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke-width="24"/>`, shaft[0], shaft[1], shaft[2], shaft[3])
+		fmt.Fprintf(bw, `<polygon points="%g,%g %g,%g %g,%g" stroke="none"/>`, head[0], head[1], head[2], head[3], head[4], head[5])
+		fmt.Fprintln(bw, `</g>`)
+	}
+	if opts.Coordinates {
+		files, ranks := l.labels()
+		fmt.Fprintln(bw, `<g font-family="sans-serif" font-size="48" text-anchor="middle" dominant-baseline="central">`)
+		for i, s := range files {
+			x, _ := l.point(float64(i), 0)
+			fmt.Fprintf(bw, `<text x="%g" y="%g">%s</text>`+"\n", x, boardHeight+l.margin/2, s)
+		}
+		for i, s := range ranks {
+			_, y := l.point(0, float64(i))
+			fmt.Fprintf(bw, `<text x="%g" y="%g">%s</text>`+"\n", l.margin/2, y, s)
+		}
+		fmt.Fprintln(bw, `</g>`)
+	}
+	fmt.Fprintln(bw, `</svg>`)
+	return bw.Flush()
+}
@@ -0,0 +1,69 @@
+// Package assets 是棋盘和棋子的原始图片，不依赖 ebiten，可以在没有显示器的环境中使用。
+package assets
+
+import (
+	"bytes"
+	"embed"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"log"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// FS 是棋盘和棋子的图片，文件逐个列出，避免把 Go 源文件和目录中的其他文件也编译进去
+//
+//go:embed bg.jpg board.png piece.png
+var FS embed.FS
+
+// PieceSize 是棋子图片的边长，也是棋盘上相邻两条线的距离
+const PieceSize = 160
+
+var (
+	Background image.Image
+	Board      image.Image
+	// Pieces 是竖着排列的所有棋子图片，依次是红方的车马相仕帅炮兵、黑方的车马象士将炮卒和两种选择框
+	Pieces image.Image
+)
+
+func decode(name string) image.Image {
+	data, err := FS.ReadFile(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return img
+}
+
+func init() {
+	Background = decode("bg.jpg")
+	Board = decode("board.png")
+	Pieces = decode("piece.png")
+}
+
+// 棋子在 piece.png 中从上到下的顺序
+var pieceOrder = [8]int{chess.Pawn: 6, chess.Cannon: 5, chess.Rook: 0, chess.Knight: 1, chess.Bishop: 2, chess.Advisor: 3, chess.King: 4}
+
+// 选择框在 piece.png 中的位置
+const (
+	RedBox  = 14
+	BlueBox = 15
+)
+
+// Tile 返回 piece.png 中第 i 个图片的区域
+func Tile(i int) image.Rectangle {
+	return image.Rect(0, PieceSize*i, PieceSize, PieceSize*(i+1))
+}
+
+// PieceTile 返回棋子图片在 piece.png 中的区域
+func PieceTile(piece *chess.Piece) image.Rectangle {
+	i := pieceOrder[piece.PieceType]
+	if piece.Color == chess.Black {
+		i += 7
+	}
+	return Tile(i)
+}
@@ -1,21 +1,21 @@
 package resources
 
 import (
-	"embed"
+	_ "embed"
+
 	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/resources/assets"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
-	"image"
-	_ "image/jpeg"
-	_ "image/png"
 	"log"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
-//go:embed assets
-var f embed.FS
+// fontFile 是界面使用的马善政毛笔楷书字体 (SIL OFL)，需要放在 assets 目录中，缺少时编译失败
+//
+//go:embed assets/MaShanZheng-Regular.ttf
+var fontFile []byte
 
 var (
 	RedRookImage           *ebiten.Image
@@ -40,56 +40,29 @@ var (
 )
 
 func init() {
-	backgroundFile, err := f.Open("assets/bg.jpg")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer backgroundFile.Close()
-	BackgroundImage, _, err = ebitenutil.NewImageFromReader(backgroundFile)
-	if err != nil {
-		log.Fatal(err)
+	BackgroundImage = ebiten.NewImageFromImage(assets.Background)
+	BoardImage = ebiten.NewImageFromImage(assets.Board)
+	allPieces := ebiten.NewImageFromImage(assets.Pieces)
+	tile := func(i int) *ebiten.Image {
+		return allPieces.SubImage(assets.Tile(i)).(*ebiten.Image)
 	}
+	RedRookImage = tile(0)
+	RedKnightImage = tile(1)
+	RedBishopImage = tile(2)
+	RedAdvisorImage = tile(3)
+	RedKingImage = tile(4)
+	RedCannonImage = tile(5)
+	RedPawnImage = tile(6)
+	BlackRookImage = tile(7)
+	BlackKnightImage = tile(8)
+	BlackBishopImage = tile(9)
+	BlackAdvisorImage = tile(10)
+	BlackKingImage = tile(11)
+	BlackCannonImage = tile(12)
+	BlackPawnImage = tile(13)
+	RedBoxImage = tile(assets.RedBox)
+	BlueBoxImage = tile(assets.BlueBox)
 
-	boardFile, err := f.Open("assets/board.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer boardFile.Close()
-	BoardImage, _, err = ebitenutil.NewImageFromReader(boardFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	pieceFile, err := f.Open("assets/piece.png")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer pieceFile.Close()
-	allPieces, _, err := ebitenutil.NewImageFromReader(pieceFile)
-	if err != nil {
-		log.Fatal(err)
-	}
-	RedRookImage = allPieces.SubImage(image.Rect(0, 0, 160, 160)).(*ebiten.Image)
-	RedKnightImage = allPieces.SubImage(image.Rect(0, 160*1, 160, 160*2)).(*ebiten.Image)
-	RedBishopImage = allPieces.SubImage(image.Rect(0, 160*2, 160, 160*3)).(*ebiten.Image)
-	RedAdvisorImage = allPieces.SubImage(image.Rect(0, 160*3, 160, 160*4)).(*ebiten.Image)
-	RedKingImage = allPieces.SubImage(image.Rect(0, 160*4, 160, 160*5)).(*ebiten.Image)
-	RedCannonImage = allPieces.SubImage(image.Rect(0, 160*5, 160, 160*6)).(*ebiten.Image)
-	RedPawnImage = allPieces.SubImage(image.Rect(0, 160*6, 160, 160*7)).(*ebiten.Image)
-	BlackRookImage = allPieces.SubImage(image.Rect(0, 160*7, 160, 160*8)).(*ebiten.Image)
-	BlackKnightImage = allPieces.SubImage(image.Rect(0, 160*8, 160, 160*9)).(*ebiten.Image)
-	BlackBishopImage = allPieces.SubImage(image.Rect(0, 160*9, 160, 160*10)).(*ebiten.Image)
-	BlackAdvisorImage = allPieces.SubImage(image.Rect(0, 160*10, 160, 160*11)).(*ebiten.Image)
-	BlackKingImage = allPieces.SubImage(image.Rect(0, 160*11, 160, 160*12)).(*ebiten.Image)
-	BlackCannonImage = allPieces.SubImage(image.Rect(0, 160*12, 160, 160*13)).(*ebiten.Image)
-	BlackPawnImage = allPieces.SubImage(image.Rect(0, 160*13, 160, 160*14)).(*ebiten.Image)
-	RedBoxImage = allPieces.SubImage(image.Rect(0, 160*14, 160, 160*15)).(*ebiten.Image)
-	BlueBoxImage = allPieces.SubImage(image.Rect(0, 160*15, 160, 160*16)).(*ebiten.Image)
-
-	fontFile, err := f.ReadFile("assets/MaShanZheng-Regular.ttf")
-	if err != nil {
-		log.Fatal(err)
-	}
 	tt, err := opentype.Parse(fontFile)
 	if err != nil {
 		log.Fatal(err)
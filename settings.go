@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/ui"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Settings 是新对局的设置，点击“新局”时生效
+type Settings struct {
+	// VsEngine 为 true 时和电脑下棋，否则双方都由玩家走子
+	VsEngine bool
+	// PlayerColor 是和电脑下棋时玩家执的一方
+	PlayerColor bool
+	// Level 是电脑的难度，1-20
+	Level int
+}
+
+var DefaultSettings = Settings{
+	VsEngine:    true,
+	PlayerColor: chess.Red,
+	Level:       10,
+}
+
+// tag 把设置记录在棋谱的标签中，继续上局时可以恢复
+func (s Settings) tag(record *game.Game) {
+	red, black := "玩家", "玩家"
+	if s.VsEngine && s.PlayerColor == chess.Red {
+		black = "电脑"
+	} else if s.VsEngine {
+		red = "电脑"
+	}
+	record.SetTag("Red", red)
+	record.SetTag("Black", black)
+	opponent := "Human"
+	if s.VsEngine {
+		opponent = "Engine"
+	}
+	record.SetTag("Opponent", opponent)
+	color := "Red"
+	if s.PlayerColor == chess.Black {
+		color = "Black"
+	}
+	record.SetTag("PlayerColor", color)
+	record.SetTag("Level", fmt.Sprint(s.Level))
+}
+
+// settingsFromTags 读取 tag 记录的设置，没有记录的项使用默认设置
+func settingsFromTags(record *game.Game) Settings {
+	s := DefaultSettings
+	switch record.Tag("Opponent") {
+	case "Engine":
+		s.VsEngine = true
+	case "Human":
+		s.VsEngine = false
+	}
+	switch record.Tag("PlayerColor") {
+	case "Red":
+		s.PlayerColor = chess.Red
+	case "Black":
+		s.PlayerColor = chess.Black
+	}
+	if level, err := strconv.Atoi(record.Tag("Level")); err == nil && level >= 1 && level <= engine.MaxSkillLevel {
+		s.Level = level
+	}
+	return s
+}
+
+// EngineMoveTime 是电脑每步的思考时间，低难度时搜索会更早结束
+const EngineMoveTime = time.Second
+
+type button struct {
+	image   *ebiten.Image
+	x, y    int
+	onClick func()
+}
+
+func (b *button) contains(x, y int) bool {
+	w, h := b.image.Size()
+	return x >= b.x && x < b.x+w && y >= b.y && y < b.y+h
+}
+
+// layoutButtons 根据当前设置重新生成底栏的按钮
+func (g *Game) layoutButtons() {
+	opponent := "双人"
+	if g.settings.VsEngine {
+		opponent = "人机"
+	}
+	color := "执红"
+	if g.settings.PlayerColor == chess.Black {
+		color = "执黑"
+	}
+	analysis := "分析"
+	if g.analyzer.enabled {
+		analysis = "停止分析"
+	}
+	type item struct {
+		label   string
+		onClick func()
+	}
+	items := []item{{"新局", g.NewRound}}
+	if g.saved != nil {
+		items = append(items, item{"继续上局", g.Resume})
+	}
+	items = append(items,
+		item{opponent, func() { g.settings.VsEngine = !g.settings.VsEngine }},
+		item{color, func() { g.settings.PlayerColor = !g.settings.PlayerColor }},
+		item{fmt.Sprintf("难度 %d", g.settings.Level), func() {
+			g.settings.Level = g.settings.Level%engine.MaxSkillLevel + 1
+		}},
+		item{analysis, func() { g.analyzer.enabled = !g.analyzer.enabled }},
+	)
+	g.buttons = g.buttons[:0]
+	x := 40
+	for _, item := range items {
+		image := ui.Button(item.label)
+		g.buttons = append(g.buttons, &button{image: image, x: x, y: BoardHeight + 20, onClick: item.onClick})
+		w, _ := image.Size()
+		x += w + 30
+	}
+}
+
+// NewRound 按照当前设置开始新的一局，正在进行的搜索会被停止
+func (g *Game) NewRound() {
+	record := game.NewGame()
+	g.settings.tag(record)
+	record.SetTag("Date", time.Now().Format("2006.01.02"))
+	g.start(record, g.settings)
+}
+
+// start 从棋谱的主线末尾开始下棋
+func (g *Game) start(record *game.Game, settings Settings) {
+	g.stopThinking()
+	cursor, err := game.NewCursor(record)
+	if err != nil {
+		cursor, _ = game.NewCursor(game.NewGame())
+	}
+	cursor.ToEnd()
+	g.record = cursor
+	g.board = cursor.Board()
+	g.fromSquare = 0
+	g.result = g.board.Result()
+	g.paused = false
+	g.engine.NewGame()
+	g.engine.SetOption("SkillLevel", fmt.Sprint(settings.Level))
+	g.settings = settings
+	g.round = settings
+}
+
+func (g *Game) stopThinking() {
+	if g.thinking != nil {
+		g.engine.Stop()
+		<-g.thinking
+		g.thinking = nil
+	}
+}
+
+// engineTurn 判断当前是否轮到电脑走棋，暂停时由玩家替电脑走
+func (g *Game) engineTurn() bool {
+	return g.round.VsEngine && !g.paused && g.board.Turn() != g.round.PlayerColor && g.result == chess.NoResult
+}
+
+// updateEngine 在轮到电脑时开始搜索，搜索结束后走出结果
+func (g *Game) updateEngine() {
+	if !g.engineTurn() {
+		return
+	}
+	if g.thinking == nil {
+		g.thinking = g.engine.Go(g.board, engine.Limits{MoveTime: EngineMoveTime})
+		return
+	}
+	select {
+	case result := <-g.thinking:
+		g.thinking = nil
+		if result.BestMove != nil {
+			g.play(result.BestMove)
+		} else {
+			g.result = g.board.Result()
+		}
+	default:
+	}
+}
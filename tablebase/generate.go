@@ -0,0 +1,111 @@
+package tablebase
+
+import (
+	"github.com/clysto/gochess/chess"
+)
+
+// event 表示在第 distance 步时某个局面的结果已经确定，或者某个局面的一个吃子后继已知结果
+type event struct {
+	parent int32
+	// node 为 -1 时 child 给出吃子后继的结果，否则表示 node 自身的结果已确定
+	node  int32
+	child WDL
+}
+
+// Generate 用逆向分析生成签名 sig 的残局库，吃子后进入的子力组合会先被生成并加入 tb
+func (tb *Tablebase) Generate(sig string) (*Table, error) {
+	t, err := newTable(sig)
+	if err != nil {
+		return nil, err
+	}
+	if existing := tb.Table(t.Signature); existing != nil {
+		return existing, nil
+	}
+
+	n := t.Size()
+	t.values = make([]byte, n)
+	remaining := make([]int32, n)
+	predecessors := make([][]int32, n)
+	buckets := make([][]event, maxDistance+2)
+
+	for idx := 0; idx < n; idx++ {
+		b := t.board(idx)
+		if b == nil || illegalPosition(b) {
+			t.values[idx] = valueIllegal
+			continue
+		}
+		moves := b.LegalMoves()
+		if len(moves) == 0 {
+			// 无子可走判负
+			t.values[idx] = 1
+			buckets[0] = append(buckets[0], event{parent: -1, node: int32(idx)})
+			continue
+		}
+		remaining[idx] = int32(len(moves))
+		for _, move := range moves {
+			capture := b.IsCapture(move)
+			b.Push(move)
+			if capture {
+				wdl, dtm, ok, err := tb.probeOrGenerate(b)
+				if err != nil {
+					return nil, err
+				}
+				if ok && wdl != Draw {
+					buckets[dtm] = append(buckets[dtm], event{parent: int32(idx), node: -1, child: wdl})
+				}
+			} else {
+				child := t.index(b)
+				predecessors[child] = append(predecessors[child], int32(idx))
+			}
+			b.Pop()
+		}
+	}
+
+	// 按距离从小到大处理，保证每个局面第一次确定的结果就是最短的杀棋距离
+	resolve := func(parent int32, child WDL, distance int) {
+		if t.values[parent] != valueDraw || distance+1 > maxDistance {
+			return
+		}
+		if child == Loss {
+			t.values[parent] = byte(distance + 2)
+			buckets[distance+1] = append(buckets[distance+1], event{parent: -1, node: parent})
+		} else if remaining[parent]--; remaining[parent] == 0 {
+			t.values[parent] = byte(distance + 2)
+			buckets[distance+1] = append(buckets[distance+1], event{parent: -1, node: parent})
+		}
+	}
+	for distance := 0; distance <= maxDistance; distance++ {
+		for i := 0; i < len(buckets[distance]); i++ {
+			e := buckets[distance][i]
+			if e.node < 0 {
+				resolve(e.parent, e.child, distance)
+				continue
+			}
+			wdl, _, _ := decodeValue(t.values[e.node])
+			for _, parent := range predecessors[e.node] {
+				resolve(parent, wdl, distance)
+			}
+		}
+		buckets[distance] = nil
+	}
+
+	tb.Add(t)
+	return t, nil
+}
+
+func (tb *Tablebase) probeOrGenerate(b *chess.Board) (WDL, int, bool, error) {
+	sig, _ := Signature(b)
+	if tb.Table(sig) == nil {
+		if _, err := tb.Generate(sig); err != nil {
+			return Draw, 0, false, err
+		}
+	}
+	wdl, dtm, ok := tb.Probe(b)
+	return wdl, dtm, ok, nil
+}
+
+// illegalPosition 判断不该走棋的一方是否被将军或者将帅照面
+func illegalPosition(b *chess.Board) bool {
+	king := b.King(!b.Turn())
+	return b.KingsFacing() || b.IsAttackedBy(b.Turn(), king)
+}
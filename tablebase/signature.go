@@ -0,0 +1,117 @@
+package tablebase
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 子力签名中棋子的排列顺序，例如 KRNvKAB
+var pieceOrder = []uint8{chess.King, chess.Rook, chess.Knight, chess.Cannon, chess.Pawn, chess.Advisor, chess.Bishop}
+
+var pieceLetters = map[uint8]byte{
+	chess.King:    'K',
+	chess.Rook:    'R',
+	chess.Knight:  'N',
+	chess.Cannon:  'C',
+	chess.Pawn:    'P',
+	chess.Advisor: 'A',
+	chess.Bishop:  'B',
+}
+
+var pieceStrength = map[uint8]int{
+	chess.Rook:    9,
+	chess.Knight:  4,
+	chess.Cannon:  4,
+	chess.Pawn:    1,
+	chess.Advisor: 0,
+	chess.Bishop:  0,
+}
+
+func sidePieces(b *chess.Board, color bool) []uint8 {
+	var pieces []uint8
+	for _, pieceType := range pieceOrder {
+		for range chess.ScanReversed(b.Pieces(pieceType, color)) {
+			pieces = append(pieces, pieceType)
+		}
+	}
+	return pieces
+}
+
+func sideString(pieces []uint8) string {
+	s := make([]byte, len(pieces))
+	for i, pieceType := range pieces {
+		s[i] = pieceLetters[pieceType]
+	}
+	return string(s)
+}
+
+func sideStrength(pieces []uint8) int {
+	n := 0
+	for _, pieceType := range pieces {
+		n += pieceStrength[pieceType]
+	}
+	return n
+}
+
+// strongerFirst 决定签名中哪一方在前：进攻子力强的一方在前，相同时按字符串比较
+func strongerFirst(red, black []uint8) bool {
+	if sideStrength(red) != sideStrength(black) {
+		return sideStrength(red) > sideStrength(black)
+	}
+	return sideString(red) >= sideString(black)
+}
+
+// Signature 返回局面的子力签名，flipped 为 true 时签名中的第一方是黑方，
+// 查表前需要用 FlipColors 把局面转换过来
+func Signature(b *chess.Board) (sig string, flipped bool) {
+	red, black := sidePieces(b, chess.Red), sidePieces(b, chess.Black)
+	if strongerFirst(red, black) {
+		return sideString(red) + "v" + sideString(black), false
+	}
+	return sideString(black) + "v" + sideString(red), true
+}
+
+// parseSignature 解析 KRvKAA 形式的签名，返回规范化的签名和双方棋子
+func parseSignature(sig string) (string, []uint8, []uint8, error) {
+	parts := strings.Split(strings.ToUpper(sig), "V")
+	if len(parts) != 2 {
+		return "", nil, nil, fmt.Errorf("tablebase: invalid signature %q", sig)
+	}
+	var sides [2][]uint8
+	for i, part := range parts {
+		kings := 0
+		for j := 0; j < len(part); j++ {
+			found := false
+			for pieceType, letter := range pieceLetters {
+				if part[j] == letter {
+					sides[i] = append(sides[i], pieceType)
+					found = true
+					if pieceType == chess.King {
+						kings++
+					}
+				}
+			}
+			if !found {
+				return "", nil, nil, fmt.Errorf("tablebase: invalid piece %q in signature %q", part[j], sig)
+			}
+		}
+		if kings != 1 {
+			return "", nil, nil, fmt.Errorf("tablebase: signature %q needs exactly one king per side", sig)
+		}
+		rank := map[uint8]int{}
+		for k, pieceType := range pieceOrder {
+			rank[pieceType] = k
+		}
+		sort.SliceStable(sides[i], func(a, b int) bool {
+			return rank[sides[i][a]] < rank[sides[i][b]]
+		})
+	}
+	red, black := sides[0], sides[1]
+	if !strongerFirst(red, black) {
+		red, black = black, red
+	}
+	return sideString(red) + "v" + sideString(black), red, black, nil
+}
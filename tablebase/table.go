@@ -0,0 +1,265 @@
+package tablebase
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// 残局库文件格式（小端序）：
+//
+//	magic     [4]byte "GCTB"
+//	version   uint32
+//	sigLength uint8
+//	signature [sigLength]byte
+//	size      uint32
+//	之后是 deflate 压缩的 2*size 字节，前 size 字节是红方走棋的局面，后 size 字节是黑方走棋的局面
+//
+// 每个字节表示走子方的结果：0 为和棋，255 为非法局面，其他值减 1 是距离将杀的步数 (ply)，
+// 奇数为走子方胜，偶数为走子方负。
+const (
+	magic   = "GCTB"
+	version = 1
+
+	valueDraw    = 0
+	valueIllegal = 255
+	maxDistance  = 253
+)
+
+var ErrInvalidTable = errors.New("tablebase: invalid table file")
+
+type WDL int
+
+const (
+	Loss WDL = -1
+	Draw WDL = 0
+	Win  WDL = 1
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case Win:
+		return "win"
+	}
+	return "draw"
+}
+
+type tablePiece struct {
+	pieceType uint8
+	color     bool
+	squares   []uint8
+	index     [256]int
+}
+
+// Table 是某个子力签名的残局库，局面总是以签名中第一方为红方
+type Table struct {
+	Signature string
+	pieces    []tablePiece
+	size      int
+	values    []byte
+}
+
+func pieceDomain(pieceType uint8, color bool) []uint8 {
+	var squares []uint8
+	for rank := 0; rank < 10; rank++ {
+		for file := 0; file < 9; file++ {
+			sq := chess.Square(file, rank)
+			// 以红方视角判断，黑方的棋子上下翻转
+			red := sq
+			if !color {
+				red = chess.SquareMirror(sq)
+			}
+			r, f := chess.SquareRank(red)-3, chess.SquareFile(red)-3
+			ok := false
+			switch pieceType {
+			case chess.King:
+				ok = r <= 2 && f >= 3 && f <= 5
+			case chess.Advisor:
+				ok = r <= 2 && f >= 3 && f <= 5 && (r+f)%2 == 1
+			case chess.Bishop:
+				ok = r <= 4 && r%2 == 0 && f%2 == 0 && (r/2+f/2)%2 == 1
+			case chess.Pawn:
+				ok = r >= 5 || (r >= 3 && f%2 == 0)
+			default:
+				ok = true
+			}
+			if ok {
+				squares = append(squares, sq)
+			}
+		}
+	}
+	return squares
+}
+
+func newTable(sig string) (*Table, error) {
+	sig, red, black, err := parseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{Signature: sig, size: 1}
+	for i, side := range [][]uint8{red, black} {
+		for _, pieceType := range side {
+			p := tablePiece{
+				pieceType: pieceType,
+				color:     i == 0,
+				squares:   pieceDomain(pieceType, i == 0),
+			}
+			for sq := range p.index {
+				p.index[sq] = -1
+			}
+			for k, sq := range p.squares {
+				p.index[sq] = k
+			}
+			t.size *= len(p.squares)
+			t.pieces = append(t.pieces, p)
+		}
+	}
+	return t, nil
+}
+
+// Size 返回表中局面的数量（包括双方走棋）
+func (t *Table) Size() int {
+	return 2 * t.size
+}
+
+// index 返回局面在表中的位置，局面的子力必须和签名一致
+func (t *Table) index(b *chess.Board) int {
+	idx := 0
+	var squares []uint8
+	for i, p := range t.pieces {
+		if i == 0 || t.pieces[i-1].pieceType != p.pieceType || t.pieces[i-1].color != p.color {
+			squares = chess.ScanReversed(b.Pieces(p.pieceType, p.color))
+		}
+		if len(squares) == 0 {
+			return -1
+		}
+		k := p.index[squares[0]]
+		if k < 0 {
+			return -1
+		}
+		squares = squares[1:]
+		idx = idx*len(p.squares) + k
+	}
+	if b.Turn() == chess.Black {
+		idx += t.size
+	}
+	return idx
+}
+
+// board 根据位置还原局面，棋子重叠时返回 nil
+func (t *Table) board(idx int) *chess.Board {
+	b := chess.NewEmptyBoard()
+	if idx >= t.size {
+		b.SetTurn(chess.Black)
+		idx -= t.size
+	}
+	occupied := map[uint8]bool{}
+	for i := len(t.pieces) - 1; i >= 0; i-- {
+		p := t.pieces[i]
+		sq := p.squares[idx%len(p.squares)]
+		idx /= len(p.squares)
+		if occupied[sq] {
+			return nil
+		}
+		occupied[sq] = true
+		b.SetPieceAt(sq, &chess.Piece{PieceType: p.pieceType, Color: p.color})
+	}
+	return b
+}
+
+func decodeValue(v byte) (WDL, int, bool) {
+	switch v {
+	case valueIllegal:
+		return Draw, 0, false
+	case valueDraw:
+		return Draw, 0, true
+	}
+	dtm := int(v) - 1
+	if dtm%2 == 1 {
+		return Win, dtm, true
+	}
+	return Loss, dtm, true
+}
+
+// probe 查询规范方向的局面，返回走子方的结果和距离将杀的步数
+func (t *Table) probe(b *chess.Board) (WDL, int, bool) {
+	idx := t.index(b)
+	if idx < 0 {
+		return Draw, 0, false
+	}
+	return decodeValue(t.values[idx])
+}
+
+func (t *Table) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (t *Table) Write(w io.Writer) error {
+	header := &bytes.Buffer{}
+	header.WriteString(magic)
+	binary.Write(header, binary.LittleEndian, uint32(version))
+	header.WriteByte(byte(len(t.Signature)))
+	header.WriteString(t.Signature)
+	binary.Write(header, binary.LittleEndian, uint32(t.size))
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+	zw, err := flate.NewWriter(w, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(t.values); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(bufio.NewReader(f))
+}
+
+func Read(r io.Reader) (*Table, error) {
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:4]) != magic ||
+		binary.LittleEndian.Uint32(header[4:8]) != version {
+		return nil, ErrInvalidTable
+	}
+	sig := make([]byte, header[8])
+	if _, err := io.ReadFull(r, sig); err != nil {
+		return nil, ErrInvalidTable
+	}
+	t, err := newTable(string(sig))
+	if err != nil || t.Signature != string(sig) {
+		return nil, ErrInvalidTable
+	}
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil || int(size) != t.size {
+		return nil, ErrInvalidTable
+	}
+	t.values = make([]byte, 2*t.size)
+	if _, err := io.ReadFull(flate.NewReader(r), t.values); err != nil {
+		return nil, ErrInvalidTable
+	}
+	return t, nil
+}
@@ -0,0 +1,142 @@
+package tablebase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/clysto/gochess/chess"
+)
+
+// Extension 是残局库文件的扩展名，文件名为签名，例如 KRvK.gctb
+const Extension = ".gctb"
+
+// Tablebase 管理多个子力签名的残局库，从目录中按需加载
+type Tablebase struct {
+	dir    string
+	mu     sync.Mutex
+	tables map[string]*Table
+	// missing 记录目录中不存在的签名，避免重复访问文件系统
+	missing map[string]bool
+}
+
+func New() *Tablebase {
+	return &Tablebase{tables: map[string]*Table{}, missing: map[string]bool{}}
+}
+
+// Open 使用目录 dir 中的残局库文件
+func Open(dir string) (*Tablebase, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: dir, Err: os.ErrInvalid}
+	}
+	tb := New()
+	tb.dir = dir
+	return tb, nil
+}
+
+// Add 加入一个已经生成或读取的残局库
+func (tb *Tablebase) Add(t *Table) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tables[t.Signature] = t
+}
+
+// Signatures 返回目录中和已加入的所有签名
+func (tb *Tablebase) Signatures() []string {
+	tb.mu.Lock()
+	seen := map[string]bool{}
+	for sig := range tb.tables {
+		seen[sig] = true
+	}
+	tb.mu.Unlock()
+	if tb.dir != "" {
+		files, _ := filepath.Glob(filepath.Join(tb.dir, "*"+Extension))
+		for _, file := range files {
+			seen[strings.TrimSuffix(filepath.Base(file), Extension)] = true
+		}
+	}
+	var sigs []string
+	for sig := range seen {
+		sigs = append(sigs, sig)
+	}
+	return sigs
+}
+
+// Table 返回签名 sig 的残局库，需要时从目录中读取，没有时返回 nil
+func (tb *Tablebase) Table(sig string) *Table {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if t := tb.tables[sig]; t != nil {
+		return t
+	}
+	if tb.dir == "" || tb.missing[sig] {
+		return nil
+	}
+	t, err := Load(filepath.Join(tb.dir, sig+Extension))
+	if err != nil || t.Signature != sig {
+		tb.missing[sig] = true
+		return nil
+	}
+	tb.tables[sig] = t
+	return t
+}
+
+// Probe 查询局面 b，返回走子方的胜负和距离将杀的步数 (ply)。
+// 没有对应的残局库或局面非法时 ok 为 false
+func (tb *Tablebase) Probe(b *chess.Board) (wdl WDL, dtm int, ok bool) {
+	sig, flipped := Signature(b)
+	t := tb.Table(sig)
+	if t == nil {
+		return Draw, 0, false
+	}
+	if flipped {
+		b = b.FlipColors()
+	}
+	return t.probe(b)
+}
+
+// BestMove 返回残局库中最优的着法：能赢时选最快的杀法，要输时选最慢的，和棋时选任意保持和棋的着法。
+// 局面已经无子可走时着法为 nil
+func (tb *Tablebase) BestMove(b *chess.Board) (*chess.Move, WDL, int, bool) {
+	wdl, dtm, ok := tb.Probe(b)
+	if !ok {
+		return nil, Draw, 0, false
+	}
+	var best *chess.Move
+	bestScore := 0
+	for _, move := range b.LegalMoves() {
+		b.Push(move)
+		childWDL, childDTM, childOK := tb.Probe(b)
+		b.Pop()
+		if !childOK {
+			return nil, Draw, 0, false
+		}
+		score := 0
+		switch childWDL {
+		case Loss:
+			score = 1000 - childDTM
+		case Win:
+			score = -1000 + childDTM
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = move, score
+		}
+	}
+	return best, wdl, dtm, true
+}
+
+// MaxPieces 返回残局库中双方棋子总数的最大值
+func (tb *Tablebase) MaxPieces() int {
+	n := 0
+	for _, sig := range tb.Signatures() {
+		if pieces := len(strings.Replace(sig, "v", "", 1)); pieces > n {
+			n = pieces
+		}
+	}
+	return n
+}
@@ -0,0 +1,184 @@
+package tablebase
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+)
+
+func generate(t *testing.T, sigs ...string) *Tablebase {
+	tb := New()
+	for _, sig := range sigs {
+		if _, err := tb.Generate(sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tb
+}
+
+func probeFen(t *testing.T, tb *Tablebase, fen string) (WDL, int) {
+	b, err := chess.NewBoardFromFen(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wdl, dtm, ok := tb.Probe(b)
+	if !ok {
+		t.Fatalf("probe %s failed", fen)
+	}
+	return wdl, dtm
+}
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		fen     string
+		sig     string
+		flipped bool
+	}{
+		{"4k4/9/9/9/9/9/9/9/9/R3K4 w", "KRvK", false},
+		{"3ak4/9/9/9/9/9/9/9/4r4/4K4 w", "KRAvK", true},
+		{"2bak4/9/9/9/9/9/9/2C6/4P4/4K4 w", "KCPvKAB", false},
+	}
+	for _, test := range tests {
+		b, err := chess.NewBoardFromFen(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, flipped := Signature(b)
+		if sig != test.sig || flipped != test.flipped {
+			t.Errorf("%s: got %s %v, want %s %v", test.fen, sig, flipped, test.sig, test.flipped)
+		}
+	}
+	if sig, _, _, err := parseSignature("kvkr"); err != nil || sig != "KRvK" {
+		t.Errorf("parseSignature(kvkr) = %q, %v", sig, err)
+	}
+	if _, _, _, err := parseSignature("KRvR"); err == nil {
+		t.Error("signature without king should fail")
+	}
+}
+
+func TestRookWins(t *testing.T) {
+	tb := generate(t, "KRvK")
+	table := tb.Table("KRvK")
+	for idx := 0; idx < table.size; idx++ {
+		wdl, _, ok := decodeValue(table.values[idx])
+		if ok && wdl != Win {
+			t.Fatalf("%s: got %v, want win", table.board(idx).Fen(), wdl)
+		}
+	}
+	// 黑方先走可以吃掉没有保护的车
+	if wdl, _ := probeFen(t, tb, "4k4/4R4/9/9/9/9/9/9/9/3K5 b"); wdl != Draw {
+		t.Errorf("got %v, want draw", wdl)
+	}
+	// 黑方有车时查表需要交换颜色
+	if wdl, dtm := probeFen(t, tb, "3k5/9/9/9/9/9/9/9/8r/4K4 b"); wdl != Win || dtm%2 != 1 {
+		t.Errorf("got %v %d, want win", wdl, dtm)
+	}
+}
+
+func TestCannonDraws(t *testing.T) {
+	tb := generate(t, "KCvK")
+	for _, v := range tb.Table("KCvK").values {
+		if wdl, _, ok := decodeValue(v); ok && wdl != Draw {
+			t.Fatalf("got %v, want only draws", wdl)
+		}
+	}
+}
+
+func TestPawn(t *testing.T) {
+	tb := generate(t, "KPvK")
+	// 高兵能赢，底兵只能和
+	if wdl, _ := probeFen(t, tb, "3k5/9/9/4P4/9/9/9/9/9/4K4 w"); wdl != Win {
+		t.Errorf("high pawn: got %v, want win", wdl)
+	}
+	if wdl, _ := probeFen(t, tb, "3k1P3/9/9/9/9/9/9/9/9/4K4 w"); wdl != Draw {
+		t.Errorf("bottom pawn: got %v, want draw", wdl)
+	}
+}
+
+// 检查每个胜局都有走到 dtm-1 负局的着法，每个负局的所有着法都走到对方胜局且最长为 dtm-1
+func TestDistanceToMate(t *testing.T) {
+	tb := generate(t, "KPvK", "KRvKA")
+	for _, sig := range []string{"KPvK", "KRvKA"} {
+		table := tb.Table(sig)
+		for idx := 0; idx < table.Size(); idx += 7 {
+			wdl, dtm, ok := decodeValue(table.values[idx])
+			if !ok || wdl == Draw {
+				continue
+			}
+			b := table.board(idx)
+			best := -1
+			for _, move := range b.LegalMoves() {
+				b.Push(move)
+				childWDL, childDTM, _ := tb.Probe(b)
+				b.Pop()
+				if wdl == Win && childWDL == Loss && (best < 0 || childDTM < best) {
+					best = childDTM
+				}
+				if wdl == Loss {
+					if childWDL != Win {
+						t.Fatalf("%s: move %v escapes a lost position", b.Fen(), move)
+					}
+					if childDTM > best {
+						best = childDTM
+					}
+				}
+			}
+			if best != dtm-1 {
+				t.Fatalf("%s: %v in %d, best child %d", b.Fen(), wdl, dtm, best)
+			}
+		}
+	}
+}
+
+func TestBestMove(t *testing.T) {
+	tb := generate(t, "KRvKA")
+	b, _ := chess.NewBoardFromFen("4k4/4a4/9/9/9/9/9/9/9/R2K5 w")
+	_, wdl, dtm, ok := tb.BestMove(b)
+	if !ok || wdl != Win {
+		t.Fatalf("got %v %v, want win", wdl, ok)
+	}
+	for ply := 0; ply < dtm; ply++ {
+		move, _, _, _ := tb.BestMove(b)
+		b.Push(move)
+	}
+	if !b.IsCheckmate() {
+		t.Errorf("%s is not checkmate after %d plies", b.Fen(), dtm)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	tb := generate(t, "KPvK")
+	table := tb.Table("KPvK")
+	buf := &bytes.Buffer{}
+	if err := table.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Signature != table.Signature || !bytes.Equal(loaded.values, table.values) {
+		t.Fatal("loaded table differs")
+	}
+
+	dir := t.TempDir()
+	if err := table.Save(filepath.Join(dir, "KPvK"+Extension)); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wdl, _ := probeFen(t, opened, "3k5/9/9/4P4/9/9/9/9/9/4K4 w"); wdl != Win {
+		t.Errorf("got %v, want win", wdl)
+	}
+	if _, err := Read(bytes.NewReader([]byte("GCBK"))); err != ErrInvalidTable {
+		t.Errorf("got %v, want ErrInvalidTable", err)
+	}
+	if _, err := Open(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Package tune 用 Texel 方法调整手写评估函数的参数：
+// 对带有对局结果的局面，最小化结果和 sigmoid(K × 评估分数) 之间的均方误差。
+package tune
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+// Position 是一个带标签的局面，Result 以红方为视角：1 为红胜，0.5 为和，0 为黑胜
+type Position struct {
+	Board  *chess.Board
+	Result float64
+}
+
+func parseResult(s string) (float64, bool) {
+	switch chess.ParseResult(strings.Trim(s, `"[];`)) {
+	case chess.RedWins:
+		return 1, true
+	case chess.BlackWins:
+		return 0, true
+	case chess.Draw:
+		return 0.5, true
+	}
+	f, err := strconv.ParseFloat(strings.Trim(s, `"[];`), 64)
+	if err != nil || f < 0 || f > 1 {
+		return 0, false
+	}
+	return f, true
+}
+
+// ReadPositions 读取局面文件，每行是 FEN 和结果 (1-0, 0-1, 1/2-1/2 或者 0 到 1 之间的小数)，
+// 以 # 开头的行会被忽略
+func ReadPositions(r io.Reader) ([]Position, error) {
+	var positions []Position
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("tune: line %d: expected FEN and result", line)
+		}
+		result, ok := parseResult(fields[len(fields)-1])
+		if !ok {
+			return nil, fmt.Errorf("tune: line %d: invalid result %q", line, fields[len(fields)-1])
+		}
+		b, err := chess.NewBoardFromFen(strings.Join(fields[:len(fields)-1], " "))
+		if err != nil {
+			return nil, fmt.Errorf("tune: line %d: %v", line, err)
+		}
+		positions = append(positions, Position{Board: b, Result: result})
+	}
+	return positions, scanner.Err()
+}
+
+func LoadPositions(path string) ([]Position, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadPositions(f)
+}
+
+// 参数向量的布局：先是 8 个子力价值，然后是 8 × 10 × 9 个位置分
+const (
+	pstOffset  = 8
+	paramCount = pstOffset + 8*90
+)
+
+func getParam(p *engine.Params, i int) *int {
+	if i < pstOffset {
+		return &p.PieceValues[i]
+	}
+	i -= pstOffset
+	return &p.PieceSquareTables[i/90][i%90/9][i%9]
+}
+
+// term 表示某个参数在一个局面的评估中出现的次数，红方棋子为正，黑方棋子为负
+type term struct {
+	position int
+	count    int
+}
+
+// Tuner 保存每个参数出现在哪些局面中，评估函数对参数是线性的，修改一个参数时只需要更新相关局面的分数
+type Tuner struct {
+	Params *engine.Params
+	// K 是把评估分数换算为胜率的系数
+	K float64
+
+	results []float64
+	scores  []int
+	terms   [paramCount][]term
+}
+
+// NewTuner 提取每个局面的特征，quiescence 为 true 时使用静态搜索主要变例末端的局面
+func NewTuner(params *engine.Params, positions []Position, quiescence bool) *Tuner {
+	t := &Tuner{
+		Params:  params,
+		K:       1,
+		results: make([]float64, len(positions)),
+		scores:  make([]int, len(positions)),
+	}
+	for i, pos := range positions {
+		b := pos.Board
+		if quiescence {
+			b, _ = params.QuiescenceLeaf(b)
+		}
+		t.results[i] = pos.Result
+		counts := map[int]int{}
+		for pieceType := chess.Pawn; pieceType <= chess.King; pieceType++ {
+			for _, color := range []bool{chess.Red, chess.Black} {
+				sign := 1
+				if color == chess.Black {
+					sign = -1
+				}
+				for _, sq := range chess.ScanReversed(b.Pieces(pieceType, color)) {
+					rank, file := chess.SquareRank(sq)-3, chess.SquareFile(sq)-3
+					if color == chess.Black {
+						rank = 9 - rank
+					}
+					counts[int(pieceType)] += sign
+					counts[pstOffset+int(pieceType)*90+rank*9+file] += sign
+				}
+			}
+		}
+		for param, count := range counts {
+			if count != 0 {
+				t.terms[param] = append(t.terms[param], term{position: i, count: count})
+			}
+		}
+	}
+	t.refresh()
+	return t
+}
+
+// refresh 重新计算所有局面以红方为视角的分数
+func (t *Tuner) refresh() {
+	for i := range t.scores {
+		t.scores[i] = 0
+	}
+	for param := range t.terms {
+		value := *getParam(t.Params, param)
+		for _, tm := range t.terms[param] {
+			t.scores[tm.position] += value * tm.count
+		}
+	}
+}
+
+func (t *Tuner) sigmoid(score int) float64 {
+	return 1 / (1 + math.Pow(10, -t.K*float64(score)/400))
+}
+
+// Loss 返回所有局面的均方误差
+func (t *Tuner) Loss() float64 {
+	if len(t.scores) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for i, score := range t.scores {
+		d := t.results[i] - t.sigmoid(score)
+		sum += d * d
+	}
+	return sum / float64(len(t.scores))
+}
+
+// FitK 在当前参数下用三分法找到使误差最小的 K
+func (t *Tuner) FitK() float64 {
+	lo, hi := 0.0, 4.0
+	for i := 0; i < 60; i++ {
+		m1, m2 := lo+(hi-lo)/3, hi-(hi-lo)/3
+		t.K = m1
+		l1 := t.Loss()
+		t.K = m2
+		l2 := t.Loss()
+		if l1 < l2 {
+			hi = m2
+		} else {
+			lo = m1
+		}
+	}
+	t.K = (lo + hi) / 2
+	return t.K
+}
+
+// lossDelta 返回参数 param 增加 delta 后误差总和的变化
+func (t *Tuner) lossDelta(param int, delta int) float64 {
+	sum := 0.0
+	for _, tm := range t.terms[param] {
+		score := t.scores[tm.position]
+		before := t.results[tm.position] - t.sigmoid(score)
+		after := t.results[tm.position] - t.sigmoid(score+delta*tm.count)
+		sum += after*after - before*before
+	}
+	return sum
+}
+
+func (t *Tuner) apply(param int, delta int) {
+	*getParam(t.Params, param) += delta
+	for _, tm := range t.terms[param] {
+		t.scores[tm.position] += delta * tm.count
+	}
+}
+
+// Iterate 对每个出现过的参数依次尝试 +step 和 -step，保留使误差下降的修改，返回修改的参数个数。
+// 将帅的子力价值不参与调整
+func (t *Tuner) Iterate(step int) int {
+	changed := 0
+	for param := 0; param < paramCount; param++ {
+		if param == int(chess.King) || len(t.terms[param]) == 0 {
+			continue
+		}
+		for _, delta := range []int{step, -step} {
+			if t.lossDelta(param, delta) < 0 {
+				t.apply(param, delta)
+				changed++
+				break
+			}
+		}
+	}
+	return changed
+}
+
+// Run 反复迭代直到没有参数可以改进或者达到 maxIterations，每次迭代后调用 progress
+func (t *Tuner) Run(maxIterations int, progress func(iteration int, changed int, loss float64)) {
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		changed := t.Iterate(1)
+		if progress != nil {
+			progress(iteration, changed, t.Loss())
+		}
+		if changed == 0 {
+			return
+		}
+	}
+}
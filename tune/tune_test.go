@@ -0,0 +1,86 @@
+package tune
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/engine"
+)
+
+func loadSample(t *testing.T) []Position {
+	positions, err := LoadPositions("testdata/sample.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) == 0 {
+		t.Fatal("no positions in sample")
+	}
+	return positions
+}
+
+func TestReadPositions(t *testing.T) {
+	input := `# comment
+rnbakabnr/9/1c5c1/p1p1p1p1p/9/9/P1P1P1P1P/1C5C1/9/RNBAKABNR w - - 0 1 1/2-1/2
+4k4/9/9/9/9/9/9/9/9/R3K4 w 1.0
+`
+	positions, err := ReadPositions(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 2 || positions[0].Result != 0.5 || positions[1].Result != 1 {
+		t.Fatalf("unexpected positions %+v", positions)
+	}
+	if _, err := ReadPositions(strings.NewReader("4k4/9/9/9/9/9/9/9/9/R3K4 w 2-0\n")); err == nil {
+		t.Error("expected error for invalid result")
+	}
+}
+
+// 参数化的线性分数必须和评估函数一致
+func TestScoresMatchEvaluate(t *testing.T) {
+	positions := loadSample(t)
+	params := engine.DefaultParams()
+	tuner := NewTuner(params, positions, false)
+	for i, pos := range positions {
+		want := params.Evaluate(pos.Board)
+		if pos.Board.Turn() == chess.Black {
+			want = -want
+		}
+		if tuner.scores[i] != want {
+			t.Fatalf("%s: score %d, want %d", pos.Board.Fen(), tuner.scores[i], want)
+		}
+	}
+}
+
+func TestTune(t *testing.T) {
+	positions := loadSample(t)
+	run := func() (*engine.Params, float64, float64) {
+		tuner := NewTuner(engine.DefaultParams(), positions, true)
+		tuner.FitK()
+		before := tuner.Loss()
+		tuner.Run(3, nil)
+		return tuner.Params, before, tuner.Loss()
+	}
+	params, before, after := run()
+	if after >= before {
+		t.Errorf("loss did not decrease: %f -> %f", before, after)
+	}
+	again, _, _ := run()
+	if !reflect.DeepEqual(params, again) {
+		t.Error("tuning is not deterministic")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := params.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := engine.ReadParams(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params, loaded) {
+		t.Error("params changed after write and read")
+	}
+}
@@ -0,0 +1,318 @@
+// Package xqf 读取象棋演播室 (XQStudio) 的 XQF 棋谱，包括各个版本对棋子位置和着法记录的加密。
+//
+// 文件的前 1024 字节是文件头，包括密钥、32 个棋子的位置和对局信息，之后是按先序排列的着法树，
+// 每个着法记录有 4 个字节：起点、终点、标志和保留字节，标志表示后面是否有后续着法、变着和注释。
+// 文字使用 GBK 编码。
+package xqf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var ErrInvalidFile = errors.New("xqf: invalid file")
+
+const headerSize = 1024
+
+// 版本号，0x0A 及以下没有加密，0x0B 开始加密，0x0C 开始棋子位置还会循环移位
+const (
+	versionEncrypted = 0x0B
+	versionRotated   = 0x0C
+)
+
+// 着法记录的标志位
+const (
+	flagChild   = 0x80
+	flagSibling = 0x40
+	flagComment = 0x20
+)
+
+var copyright = []byte("[(C) Copyright Mr. Dong Shiwei.]")
+
+// pieces 是文件头中 32 个棋子的顺序，前 16 个是红方
+var pieces = [16]uint8{
+	chess.Rook, chess.Knight, chess.Bishop, chess.Advisor, chess.King, chess.Advisor, chess.Bishop, chess.Knight, chess.Rook,
+	chess.Cannon, chess.Cannon, chess.Pawn, chess.Pawn, chess.Pawn, chess.Pawn, chess.Pawn,
+}
+
+// 文件头中的字段偏移
+const (
+	offsetVersion   = 2
+	offsetKeyMask   = 3
+	offsetKeyOr     = 8
+	offsetKeysSum   = 12
+	offsetKeyXY     = 13
+	offsetKeyXYf    = 14
+	offsetKeyXYt    = 15
+	offsetPieces    = 16
+	offsetResult    = 51
+	offsetTitle     = 80
+	offsetEvent     = 208
+	offsetDate      = 272
+	offsetSite      = 288
+	offsetRed       = 304
+	offsetBlack     = 320
+	offsetOpening   = 336
+	offsetAnnotator = 464
+	offsetAuthor    = 480
+)
+
+// keys 是从文件头计算出的解密参数
+type keys struct {
+	piece   byte
+	from    byte
+	to      byte
+	comment int32
+	stream  [32]byte
+}
+
+func square54Plus221(x byte) byte {
+	return x*x*54 + 221
+}
+
+func newKeys(header []byte) *keys {
+	k := &keys{}
+	if header[offsetVersion] < versionEncrypted {
+		return k
+	}
+	k.piece = square54Plus221(header[offsetKeyXY]) * header[offsetKeyXY]
+	k.from = square54Plus221(header[offsetKeyXYf]) * k.piece
+	k.to = square54Plus221(header[offsetKeyXYt]) * k.from
+	k.comment = (int32(header[offsetKeysSum])*256+int32(header[offsetKeyXY]))%32000 + 767
+	mask := header[offsetKeyMask]
+	for i := range k.stream {
+		k.stream[i] = copyright[i] & (header[offsetKeysSum+i%4]&mask | header[offsetKeyOr+i%4])
+	}
+	return k
+}
+
+// decoder 依次解密文件头之后的数据
+type decoder struct {
+	data  []byte
+	pos   int
+	keys  *keys
+	depth int
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, ErrInvalidFile
+	}
+	b := make([]byte, n)
+	for i := range b {
+		// 文件头的长度是 32 的倍数，密钥流从文件开头算起和从文件头之后算起是一样的
+		b[i] = d.data[d.pos] - d.keys.stream[d.pos%32]
+		d.pos++
+	}
+	return b, nil
+}
+
+func decodeText(b []byte) string {
+	s, err := simplifiedchinese.GBK.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(s)
+}
+
+// pascalString 读取文件头中第一个字节表示长度的字符串
+func pascalString(header []byte, offset int, size int) string {
+	n := int(header[offset])
+	if n >= size {
+		n = size - 1
+	}
+	return decodeText(header[offset+1 : offset+1+n])
+}
+
+// square 把 XQF 的坐标 (纵线 × 10 + 横线，从红方左下角开始) 转换为棋盘上的格子
+func square(xy byte) (uint8, bool) {
+	if xy > 89 {
+		return 0, false
+	}
+	return chess.Square(int(xy/10), int(xy%10)), true
+}
+
+// record 是一个解密后的着法记录
+type record struct {
+	from, to byte
+	flags    byte
+	comment  string
+}
+
+func (d *decoder) record(version byte) (*record, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return nil, err
+	}
+	r := &record{from: b[0] - 24 - d.keys.from, to: b[1] - 32 - d.keys.to}
+	hasComment := true
+	if version < versionEncrypted {
+		// 旧版本用标志的高 4 位表示后续着法，低 4 位表示变着，并且总是有注释长度
+		if b[2]&0xf0 != 0 {
+			r.flags |= flagChild
+		}
+		if b[2]&0x0f != 0 {
+			r.flags |= flagSibling
+		}
+	} else {
+		r.flags = b[2] & 0xe0
+		hasComment = r.flags&flagComment != 0
+	}
+	if hasComment {
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		n := int32(binary.LittleEndian.Uint32(b)) - d.keys.comment
+		text, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		r.comment = decodeText(text)
+	}
+	return r, nil
+}
+
+// readTree 读取 parent 的子节点和之后的兄弟节点，b 是 parent 的局面
+func (d *decoder) readTree(version byte, parent *game.Node, b *chess.Board) error {
+	for {
+		// 着法树的深度不会超过文件的长度，这里限制递归深度防止损坏的文件耗尽栈空间
+		if d.depth++; d.depth > 10000 {
+			return ErrInvalidFile
+		}
+		r, err := d.record(version)
+		if err != nil {
+			return err
+		}
+		from, ok1 := square(r.from)
+		to, ok2 := square(r.to)
+		move := &chess.Move{FromSquare: from, ToSquare: to}
+		if !ok1 || !ok2 || !b.IsLegal(move) {
+			return fmt.Errorf("xqf: illegal move %s at %s", move, b.Fen())
+		}
+		node := parent.Add(move)
+		node.Comment = r.comment
+		if r.flags&flagChild != 0 {
+			b.Push(move)
+			err := d.readTree(version, node, b)
+			b.Pop()
+			if err != nil {
+				return err
+			}
+		}
+		d.depth--
+		if r.flags&flagSibling == 0 {
+			return nil
+		}
+	}
+}
+
+// Read 读取一个 XQF 棋谱，着法树中的每一步都会检查是否合法
+func Read(r io.Reader) (*game.Game, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize || !bytes.Equal(data[:2], []byte("XQ")) {
+		return nil, ErrInvalidFile
+	}
+	header := data[:headerSize]
+	version := header[offsetVersion]
+	k := newKeys(header)
+
+	var positions [32]byte
+	for i := range positions {
+		xy := header[offsetPieces+i]
+		if version >= versionRotated {
+			positions[(int(k.piece)+1+i)%32] = xy - k.piece
+		} else {
+			positions[i] = xy - k.piece
+		}
+	}
+	b := chess.NewEmptyBoard()
+	for i, xy := range positions {
+		sq, ok := square(xy)
+		if !ok {
+			continue
+		}
+		if b.PieceAt(sq) != nil {
+			return nil, ErrInvalidFile
+		}
+		b.SetPieceAt(sq, &chess.Piece{PieceType: pieces[i%16], Color: i < 16})
+	}
+
+	d := &decoder{data: data, pos: headerSize, keys: k}
+	root, err := d.record(version)
+	if err != nil {
+		return nil, err
+	}
+	// 文件中没有记录先走的一方，根据第一步着法判断
+	if root.flags&flagChild != 0 && d.pos+2 <= len(data) {
+		from, ok := square(data[d.pos] - k.stream[d.pos%32] - 24 - k.from)
+		if piece := b.PieceAt(from); ok && piece != nil {
+			b.SetTurn(piece.Color)
+		}
+	}
+	// 重新从 FEN 创建局面，检查局面是否合法
+	b, err = chess.NewBoardFromFen(b.Fen())
+	if err != nil {
+		return nil, err
+	}
+
+	g := game.NewGame()
+	g.Root.Comment = root.comment
+	for _, field := range []struct {
+		name   string
+		offset int
+		size   int
+	}{
+		{"Event", offsetEvent, 64},
+		{"Site", offsetSite, 16},
+		{"Date", offsetDate, 16},
+		{"Red", offsetRed, 16},
+		{"Black", offsetBlack, 16},
+		{"Title", offsetTitle, 64},
+		{"Opening", offsetOpening, 64},
+		{"Annotator", offsetAnnotator, 16},
+		{"Author", offsetAuthor, 16},
+	} {
+		if value := pascalString(header, field.offset, field.size); value != "" {
+			g.SetTag(field.name, value)
+		}
+	}
+	if fen := b.Fen(); fen != chess.StartingFen {
+		g.SetTag("FEN", fen)
+	}
+	switch header[offsetResult] {
+	case 1:
+		g.Result = chess.RedWins
+	case 2:
+		g.Result = chess.BlackWins
+	case 3:
+		g.Result = chess.Draw
+	}
+	if root.flags&flagChild != 0 {
+		if err := d.readTree(version, g.Root, b); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func Load(path string) (*game.Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
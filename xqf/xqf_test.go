@@ -0,0 +1,244 @@
+package xqf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clysto/gochess/chess"
+	"github.com/clysto/gochess/game"
+	"github.com/clysto/gochess/pgn"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var update = flag.Bool("update", false, "rewrite the XQF files in testdata")
+
+var versions = []byte{0x0A, 0x0B, 0x0C, 0x10, 0x12}
+
+// encode 把棋谱编码为 XQF，是 Read 的逆过程，用来生成测试文件。seed 决定文件头中的密钥
+func encode(t *testing.T, g *game.Game, version byte, seed byte) []byte {
+	header := make([]byte, headerSize)
+	copy(header, "XQ")
+	header[offsetVersion] = version
+	if version >= versionEncrypted {
+		header[offsetKeyMask] = seed | 0x0f
+		for i := 0; i < 4; i++ {
+			header[offsetKeyOr+i] = seed * byte(i+3)
+			header[offsetKeysSum+i] = seed*byte(2*i+7) + 1
+		}
+	}
+	k := newKeys(header)
+
+	b, err := g.Board()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var positions [32]byte
+	for i := range positions {
+		positions[i] = 0xff
+	}
+	for _, sq := range chess.ScanReversed(b.Occupied()) {
+		piece := b.PieceAt(sq)
+		xy := byte((chess.SquareFile(sq)-3)*10 + chess.SquareRank(sq) - 3)
+		for i := range pieces {
+			slot := i
+			if !piece.Color {
+				slot += 16
+			}
+			if pieces[i] == piece.PieceType && positions[slot] == 0xff {
+				positions[slot] = xy
+				break
+			}
+		}
+	}
+	for i := range positions {
+		if version >= versionRotated {
+			header[offsetPieces+i] = positions[(int(k.piece)+1+i)%32] + k.piece
+		} else {
+			header[offsetPieces+i] = positions[i] + k.piece
+		}
+	}
+	header[offsetResult] = map[chess.Result]byte{chess.RedWins: 1, chess.BlackWins: 2, chess.Draw: 3}[g.Result]
+	for _, field := range []struct {
+		name   string
+		offset int
+	}{{"Event", offsetEvent}, {"Red", offsetRed}, {"Black", offsetBlack}} {
+		text, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(g.Tag(field.name)))
+		header[field.offset] = byte(len(text))
+		copy(header[field.offset+1:], text)
+	}
+
+	body := &bytes.Buffer{}
+	write := func(from, to byte, child, sibling bool, comment string) {
+		var flags byte
+		text, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(comment))
+		if version < versionEncrypted {
+			if child {
+				flags |= 0xf0
+			}
+			if sibling {
+				flags |= 0x0f
+			}
+		} else {
+			if child {
+				flags |= flagChild
+			}
+			if sibling {
+				flags |= flagSibling
+			}
+			if comment != "" {
+				flags |= flagComment
+			}
+		}
+		body.Write([]byte{from + 24 + k.from, to + 32 + k.to, flags, 0})
+		if version < versionEncrypted || comment != "" {
+			binary.Write(body, binary.LittleEndian, int32(len(text))+k.comment)
+			body.Write(text)
+		}
+	}
+	xy := func(sq uint8) byte {
+		return byte((chess.SquareFile(sq)-3)*10 + chess.SquareRank(sq) - 3)
+	}
+	var tree func(node *game.Node)
+	tree = func(node *game.Node) {
+		for i, child := range node.Children {
+			write(xy(child.Move.FromSquare), xy(child.Move.ToSquare), len(child.Children) > 0, i < len(node.Children)-1, child.Comment)
+			tree(child)
+		}
+	}
+	write(0, 0, len(g.Root.Children) > 0, false, g.Root.Comment)
+	tree(g.Root)
+
+	data := append(header, body.Bytes()...)
+	for i := headerSize; i < len(data); i++ {
+		data[i] += k.stream[i%32]
+	}
+	return data
+}
+
+const sample = `[Event "测试对局"]
+[Red "红方"]
+[Black "黑方"]
+[Result "1-0"]
+
+{中炮对屏风马} 1. h2e2 h9g7 (1... b7e7 {顺炮} 2. h0g2) 2. h0g2 {正常} i9h9 (2... c6c5) 3. i0h0 1-0
+`
+
+const endgame = `[Event "残局"]
+[Red "红方"]
+[Black "黑方"]
+[Result "0-1"]
+[FEN "4k4/9/9/9/9/9/9/9/3r5/5K3 b - - 0 1"]
+
+1... d1d0 {将军} 0-1
+`
+
+func read(t *testing.T, text string) *game.Game {
+	t.Helper()
+	g, err := pgn.NewReader(strings.NewReader(text)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func pgnText(t *testing.T, g *game.Game) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	if err := pgn.Write(buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// 每个版本一个测试文件，用 go test -update 重新生成
+func TestVersions(t *testing.T) {
+	want := read(t, sample)
+	for _, version := range versions {
+		path := filepath.Join("testdata", fmt.Sprintf("v%d.xqf", version))
+		if *update {
+			if err := ioutil.WriteFile(path, encode(t, want, version, version*7), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		g, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if got := pgnText(t, g); got != pgnText(t, want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", path, got, pgnText(t, want))
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	for _, text := range []string{sample, endgame} {
+		want := read(t, text)
+		for _, version := range versions {
+			for seed := 0; seed < 256; seed += 37 {
+				g, err := Read(bytes.NewReader(encode(t, want, version, byte(seed))))
+				if err != nil {
+					t.Fatalf("version %d seed %d: %v", version, seed, err)
+				}
+				if got := pgnText(t, g); got != pgnText(t, want) {
+					t.Fatalf("version %d seed %d: got\n%s\nwant\n%s", version, seed, got, pgnText(t, want))
+				}
+			}
+		}
+	}
+}
+
+// 手工拼出的 0x12 版文件，不经过 encode，按照象棋演播室的格式逐字节写出，用来检查 encode 和 Read 没有犯同样的错误。
+// 密钥 KeysSum、KeyXY、KeyXYf、KeyXYt 为 5A 21 43 42，四者之和是 256 的倍数，KeyMask 为 EF，KeyOr 为 11 22 33 44，
+// 算出棋子密钥 F3、起点密钥 19、终点密钥 AD、注释密钥 23840
+func TestHandAssembled(t *testing.T) {
+	header := make([]byte, headerSize)
+	copy(header, "XQ\x12\xef")
+	copy(header[8:], []byte{0x11, 0x22, 0x33, 0x44, 0x5a, 0x21, 0x43, 0x42})
+	// 32 个棋子循环移位 F3+1 个位置后各加上 F3：黑将 d9 (39) 是 1A，红帅 e0 (40) 是 1B，
+	// 右边的红车 i0 (80) 是 43，没有的棋子 FF 是 F2
+	pieces := []byte{
+		0x1a, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2,
+		0x1b, 0xf2, 0xf2, 0xf2, 0x43, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2, 0xf2,
+	}
+	copy(header[offsetPieces:], pieces)
+	header[offsetResult] = 1
+	copy(header[offsetRed:], "\x03Red")
+	copy(header[offsetAnnotator:], "\x09Annotator")
+	copy(header[offsetAuthor:], "\x06Author")
+	// 明文是根节点 (有后续着法和注释 "测试") 和着法 i0i9，加上从文件开头算起的密钥流
+	moves := []byte{
+		0x8c, 0xed, 0xe3, 0x00, 0x24, 0x60, 0x63, 0x40, 0x0b, 0x04, 0x2b, 0x1a,
+		0xc9, 0x46, 0x20, 0x44,
+	}
+	g, err := Read(bytes.NewReader(append(header, moves...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := g.Tag("FEN"); fen != "3k5/9/9/9/9/9/9/9/9/4K3R w - - 0 1" {
+		t.Errorf("FEN %q", fen)
+	}
+	if g.Tag("Red") != "Red" || g.Tag("Annotator") != "Annotator" || g.Tag("Author") != "Author" {
+		t.Errorf("tags %v", g.Tags)
+	}
+	if g.Root.Comment != "测试" || g.Result != chess.RedWins {
+		t.Errorf("comment %q, result %s", g.Root.Comment, g.Result)
+	}
+	if moves := g.MainLine(); len(moves) != 1 || moves[0].String() != "i0i9" {
+		t.Errorf("main line %v", moves)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	data := encode(t, read(t, sample), 0x12, 5)
+	for _, bad := range [][]byte{nil, []byte("XQ"), append([]byte("QX"), data[2:]...), data[:len(data)-3]} {
+		if _, err := Read(bytes.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %d bytes", len(bad))
+		}
+	}
+}